@@ -0,0 +1,264 @@
+/*
+MIT License
+
+Copyright (c) 2017 Simon Schmidt
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+
+package generalcryptosystem
+
+import "io"
+import "bytes"
+import "bufio"
+import "encoding/base64"
+
+/*
+ASCII armor, in the style of OpenPGP (RFC 4880, section 6.2): the data is base64
+encoded in lines of 64 characters between a BEGIN and an END line, followed by
+a line with the CRC-24 checksum of the data:
+
+	-----BEGIN GCS MESSAGE-----
+
+	R0NTRQUAAAGBMIIBfQQg...
+	=kR3x
+	-----END GCS MESSAGE-----
+
+The checksum detects data damaged in transport. It is no protection against
+modifications, the ciphertexts and signatures are authenticated anyway. The
+reader skips any text before the BEGIN line, so an armored block can be read
+from an email or a ticket.
+*/
+
+const (
+	ArmorMessage   = "GCS MESSAGE"   /* The output of Encrypt */
+	ArmorSignature = "GCS SIGNATURE" /* A Signature, see Signature.Armor */
+)
+
+const armorLineLength = 64
+
+/* CRC-24 of RFC 4880, section 6.1 */
+const (
+	crc24Init = 0xb704ce
+	crc24Poly = 0x1864cfb
+)
+
+func crc24(crc uint32, p []byte) uint32 {
+	for _,b := range p {
+		crc ^= uint32(b)<<16
+		for i := 0; i<8; i++ {
+			crc <<= 1
+			if crc&0x1000000!=0 { crc ^= crc24Poly }
+		}
+	}
+	return crc&0xffffff
+}
+
+func crc24Line(crc uint32) []byte {
+	b := []byte{byte(crc>>16),byte(crc>>8),byte(crc)}
+	return []byte("="+base64.StdEncoding.EncodeToString(b)+"\n")
+}
+
+// Splits the base64 output into lines.
+type lineWriter struct{
+	dest io.Writer
+	n    int
+}
+func (l *lineWriter) Write(p []byte) (n int, err error) {
+	for len(p)>0 {
+		i := armorLineLength-l.n
+		if i>len(p) { i = len(p) }
+		_,err = l.dest.Write(p[:i])
+		if err!=nil { return }
+		n += i
+		l.n += i
+		p = p[i:]
+		if l.n==armorLineLength {
+			_,err = l.dest.Write([]byte{'\n'})
+			if err!=nil { return }
+			l.n = 0
+		}
+	}
+	return
+}
+
+type armorWriter struct{
+	dest    io.Writer
+	clos    io.Closer
+	typ     string
+	lines   *lineWriter
+	enc     io.WriteCloser
+	crc     uint32
+	started bool
+	done    bool
+}
+
+// Returns a writer, that writes the armored data to dest, with the given type
+// in the BEGIN and END lines (ArmorMessage for the output of Encrypt). The
+// Close method must be called to write the checksum and the END line. If dest
+// is an io.Closer, it will be closed as well.
+func NewArmorWriter(dest io.Writer, blockType string) io.WriteCloser {
+	w := &armorWriter{dest:dest,typ:blockType,crc:crc24Init}
+	w.clos,_ = dest.(io.Closer)
+	w.lines = &lineWriter{dest,0}
+	w.enc = base64.NewEncoder(base64.StdEncoding,w.lines)
+	return w
+}
+func (w *armorWriter) start() error {
+	if w.started { return nil }
+	w.started = true
+	_,e := io.WriteString(w.dest,"-----BEGIN "+w.typ+"-----\n\n")
+	return e
+}
+func (w *armorWriter) Write(p []byte) (n int, err error) {
+	if w.done { return 0,io.ErrClosedPipe }
+	if err = w.start(); err!=nil { return }
+	w.crc = crc24(w.crc,p)
+	return w.enc.Write(p)
+}
+func (w *armorWriter) Close() error {
+	if w.done { return nil }
+	w.done = true
+	e := w.start()
+	if e!=nil { return e }
+	e = w.enc.Close()
+	if e!=nil { return e }
+	if w.lines.n>0 {
+		_,e = w.dest.Write([]byte{'\n'})
+		if e!=nil { return e }
+	}
+	_,e = w.dest.Write(crc24Line(w.crc))
+	if e!=nil { return e }
+	_,e = io.WriteString(w.dest,"-----END "+w.typ+"-----\n")
+	if e!=nil { return e }
+	if w.clos==nil { return nil }
+	return w.clos.Close()
+}
+
+/*
+Decodes the lines of an armored block, one at a time. The checksum is checked
+at the end, so damaged data is reported by the final Read.
+*/
+type armorReader struct{
+	src     *bufio.Reader
+	typ     string
+	crc     uint32
+	started bool
+	line    []byte
+	plain   []byte
+	e       error
+}
+
+// Returns a reader, that decodes the first armored block of the given type in
+// src (ArmorMessage for the input of Decrypt). Text before the block is
+// skipped. Reads fail with EInvalidArmor, if there is no such block or it is
+// malformed, and EArmorChecksum, if the data was damaged.
+func NewArmorReader(src io.Reader, blockType string) io.Reader {
+	return &armorReader{src:bufio.NewReader(src),typ:blockType,crc:crc24Init}
+}
+
+// Reads a line without the line ending and surrounding white space.
+func (r *armorReader) readLine() ([]byte,error) {
+	l,e := r.src.ReadSlice('\n')
+	if e==bufio.ErrBufferFull { return nil,EInvalidArmor }
+	if e==io.EOF && len(l)>0 { e = nil }
+	if e==io.EOF { return nil,EInvalidArmor }
+	return bytes.TrimSpace(l),e
+}
+
+// Skips to the BEGIN line and the optional header lines.
+func (r *armorReader) begin() error {
+	begin := []byte("-----BEGIN "+r.typ+"-----")
+	for {
+		l,e := r.readLine()
+		if e!=nil { return e }
+		if bytes.Equal(l,begin) { break }
+	}
+	/* Header lines ("Key: Value") end with an empty line. */
+	for {
+		b,e := r.src.Peek(1)
+		if e!=nil { return EInvalidArmor }
+		if b[0]!='\r' && b[0]!='\n' && !r.isHeader() { return nil }
+		l,e := r.readLine()
+		if e!=nil { return e }
+		if len(l)==0 { return nil }
+	}
+}
+func (r *armorReader) isHeader() bool {
+	b,_ := r.src.Peek(armorLineLength+4)
+	i := bytes.IndexByte(b,'\n')
+	if i>=0 { b = b[:i] }
+	return bytes.Contains(b,[]byte(": "))
+}
+
+func (r *armorReader) next() {
+	if !r.started {
+		r.started = true
+		if r.e = r.begin(); r.e!=nil { return }
+	}
+	l,e := r.readLine()
+	if e!=nil { r.e = e; return }
+	if len(l)==0 { return }
+	if l[0]=='=' {
+		/* The checksum, followed by the END line */
+		sum,e := base64.StdEncoding.DecodeString(string(l[1:]))
+		if e!=nil || len(sum)!=3 { r.e = EInvalidArmor; return }
+		end,e := r.readLine()
+		if e!=nil || !bytes.Equal(end,[]byte("-----END "+r.typ+"-----")) { r.e = EInvalidArmor; return }
+		if uint32(sum[0])<<16|uint32(sum[1])<<8|uint32(sum[2]) != r.crc { r.e = EArmorChecksum; return }
+		r.e = io.EOF
+		return
+	}
+	if len(l)%4!=0 { r.e = EInvalidArmor; return }
+	r.line = append(r.line[:0],l...)
+	n,e := base64.StdEncoding.Decode(r.line,r.line)
+	if e!=nil { r.e = EInvalidArmor; return }
+	r.plain = r.line[:n]
+	r.crc = crc24(r.crc,r.plain)
+}
+func (r *armorReader) Read(p []byte) (n int, err error) {
+	for len(r.plain)==0 && r.e==nil { r.next() }
+	n = copy(p,r.plain)
+	r.plain = r.plain[n:]
+	if len(r.plain)==0 { err = r.e }
+	return
+}
+
+// Returns the binary form of the signature (see MarshalBinary) as armored
+// block of the type ArmorSignature.
+func (sig *Signature) Armor() ([]byte,error) {
+	b,e := sig.MarshalBinary()
+	if e!=nil { return nil,e }
+	var buf bytes.Buffer
+	w := NewArmorWriter(&buf,ArmorSignature)
+	w.Write(b)
+	w.Close()
+	return buf.Bytes(),nil
+}
+
+// Parses the first armored signature in data.
+func ParseArmoredSignature(data []byte) (*Signature,error) {
+	b,e := io.ReadAll(NewArmorReader(bytes.NewReader(data),ArmorSignature))
+	if e!=nil { return nil,e }
+	sig := new(Signature)
+	e = sig.UnmarshalBinary(b)
+	if e!=nil { return nil,e }
+	return sig,nil
+}
@@ -0,0 +1,85 @@
+/*
+MIT License
+
+Copyright (c) 2017 Simon Schmidt
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+
+package generalcryptosystem
+
+import "io"
+import "bytes"
+import "strings"
+import "testing"
+import "crypto/rand"
+
+func TestCRC24(t *testing.T) {
+	for _,c := range []struct{ in string; crc uint32 }{
+		{"",0xb704ce},
+		{"123456789",0x21cf02},
+	} {
+		if v := crc24(crc24Init,[]byte(c.in)); v!=c.crc { t.Fatalf("%q: %06x",c.in,v) }
+	}
+	/* Incremental updates. */
+	if crc24(crc24(crc24Init,[]byte("1234")),[]byte("56789"))!=0x21cf02 { t.Fatal("incremental") }
+}
+
+func TestArmor(t *testing.T) {
+	pub,priv := testKeys(t,FIPS_P256)
+	for _,n := range []int{0,1,47,48,49,100000} {
+		msg := testMessage(n)
+		var buf bytes.Buffer
+		w,e := Encrypt(pub,rand.Reader,NewArmorWriter(&buf,ArmorMessage))
+		if e!=nil { t.Fatal(e) }
+		w.Write(msg)
+		if e = w.Close(); e!=nil { t.Fatal(e) }
+		s := buf.String()
+		if !strings.HasPrefix(s,"-----BEGIN GCS MESSAGE-----\n\n") || !strings.HasSuffix(s,"-----END GCS MESSAGE-----\n") { t.Fatal(n,s) }
+		for _,l := range strings.Split(s,"\n") { if len(l)>64 { t.Fatal(n,"long line") } }
+		
+		/* Surrounding text, CRLF line endings and headers. */
+		in := "Hello,\r\nhere is the file:\r\n\r\n"+strings.ReplaceAll(s,"\n","\r\n")+"\r\nbye\r\n"
+		r,e := Decrypt(priv,NewArmorReader(strings.NewReader(in),ArmorMessage))
+		if e!=nil { t.Fatal(n,e) }
+		got,e := io.ReadAll(r)
+		if e!=nil || !bytes.Equal(got,msg) { t.Fatal(n,e) }
+		hs := strings.Replace(s,"-----\n\n","-----\nComment: hi\n\n",1)
+		if _,e = io.ReadAll(NewArmorReader(strings.NewReader(hs),ArmorMessage)); e!=nil { t.Fatal(n,e) }
+		
+		lines := strings.Split(s,"\n")
+		b := []byte(lines[2])
+		if b[0]=='A' { b[0] = 'B' } else { b[0] = 'A' }
+		lines[2] = string(b)
+		if _,e = io.ReadAll(NewArmorReader(strings.NewReader(strings.Join(lines,"\n")),ArmorMessage)); e!=EArmorChecksum { t.Fatal(n,"damaged",e) }
+		if _,e = io.ReadAll(NewArmorReader(strings.NewReader(s[:len(s)-30]),ArmorMessage)); e!=EInvalidArmor { t.Fatal(n,"truncated",e) }
+		if _,e = io.ReadAll(NewArmorReader(strings.NewReader(s),ArmorSignature)); e!=EInvalidArmor { t.Fatal(n,"type",e) }
+	}
+}
+
+func TestArmorSignature(t *testing.T) {
+	pub,priv := testKeys(t,Ed25519)
+	sig := signBytes(t,priv,nil,[]byte("x"))
+	a,e := sig.Armor()
+	if e!=nil { t.Fatal(e) }
+	sig2,e := ParseArmoredSignature(a)
+	if e!=nil || !verifies(t,pub,sig2,[]byte("x")) { t.Fatal(e) }
+	if _,e = ParseArmoredSignature([]byte("nothing")); e!=EInvalidArmor { t.Fatal(e) }
+}
@@ -25,16 +25,6 @@ SOFTWARE.
 package generalcryptosystem
 
 
-/* Requirement 0 <= lastBlock < blockSize */
-/* Requirement 0 < blockSize < 256 */
-func padBlock(lastBlock, blockSize int) []byte {
-	ps := blockSize-lastBlock
-	b := byte(ps)
-	buf := make([]byte,ps)
-	for i := range buf { buf[i] = b }
-	return buf
-}
-
 /* Requirement 0 < len(b) < 256 */
 func unpadBlock(b []byte) []byte {
 	lb := len(b)
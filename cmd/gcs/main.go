@@ -0,0 +1,417 @@
+/*
+MIT License
+
+Copyright (c) 2017 Simon Schmidt
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+
+/*
+Command gcs generates keys, encrypts, decrypts, signs and verifies, using the
+generalcryptosystem package.
+
+Usage:
+
+	gcs keygen --group NAME [--passphrase-file FILE] [-o FILE]
+	gcs pubkey -k KEY [--passphrase-file FILE] [-o FILE]
+	gcs encrypt -r PUBKEY [-r PUBKEY ...] [--suite NAME] [-a] [-o FILE] [FILE]
+	gcs decrypt -k KEY [--passphrase-file FILE] [-a | --legacy] [-o FILE] [FILE]
+	gcs sign -k KEY [--passphrase-file FILE] [--manifest [--context TEXT]] [-o FILE] [FILE]
+	gcs verify -p PUBKEY -s SIGNATURE [--manifest [--context TEXT]] [FILE]
+	gcs groups
+
+Data is read from FILE, or from the standard input, and written to the -o FILE,
+or to the standard output. Keys are PEM files: PKCS #8 and PKIX where the group
+has a standard encoding, "GCS PRIVATE KEY" and "GCS PUBLIC KEY" otherwise, and
+"GCS ENCRYPTED PRIVATE KEY", if keygen was given a passphrase. Signatures are
+detached and armored. With --manifest, the signature also covers the name and
+size of FILE, the time of signing and the --context text, so it does not
+verify for another file or context. With -a, encrypt armors the ciphertext and
+decrypt reads an armored ciphertext. With --legacy, decrypt reads the older,
+unauthenticated format, which it rejects otherwise.
+
+The exit status is 0 on success, 1 if the operation failed (for example a bad
+signature, a wrong key or a modified ciphertext) and 2 on usage errors. Decrypt
+writes the plaintext to the standard output as it is authenticated, so a
+truncated or modified ciphertext might still produce some output before the
+failure is reported. The -o FILE is only replaced once the whole ciphertext is
+decrypted.
+*/
+package main
+
+import "os"
+import "io"
+import "fmt"
+import "flag"
+import "bytes"
+import "errors"
+import "strings"
+import "crypto/rand"
+import "path/filepath"
+import "encoding/pem"
+import gcs "github.com/maxymania/generalcryptosystem"
+
+const (
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+const (
+	pemPrivateKey = "GCS PRIVATE KEY"
+	pemPublicKey  = "GCS PUBLIC KEY"
+)
+
+const usage = `usage:
+	gcs keygen --group NAME [--passphrase-file FILE] [-o FILE]
+	gcs pubkey -k KEY [--passphrase-file FILE] [-o FILE]
+	gcs encrypt -r PUBKEY [-r PUBKEY ...] [--suite NAME] [-a] [-o FILE] [FILE]
+	gcs decrypt -k KEY [--passphrase-file FILE] [-a | --legacy] [-o FILE] [FILE]
+	gcs sign -k KEY [--passphrase-file FILE] [--manifest [--context TEXT]] [-o FILE] [FILE]
+	gcs verify -p PUBKEY -s SIGNATURE [--manifest [--context TEXT]] [FILE]
+	gcs groups
+`
+
+// An error in the command line.
+type usageError string
+func (u usageError) Error() string { return string(u) }
+
+var errBadSignature = errors.New("signature verification failed")
+
+var commands = map[string]func(args []string) error{
+	"keygen":  keygen,
+	"pubkey":  pubkey,
+	"encrypt": encrypt,
+	"decrypt": decrypt,
+	"sign":    sign,
+	"verify":  verify,
+	"groups":  groups,
+}
+
+func main() {
+	if len(os.Args)<2 {
+		fmt.Fprint(os.Stderr,usage)
+		os.Exit(exitUsage)
+	}
+	cmd,ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr,"gcs: unknown command %q\n%s",os.Args[1],usage)
+		os.Exit(exitUsage)
+	}
+	e := cmd(os.Args[2:])
+	if e==nil { return }
+	fmt.Fprintf(os.Stderr,"gcs %s: %v\n",os.Args[1],e)
+	if _,ok := e.(usageError); ok { os.Exit(exitUsage) }
+	os.Exit(exitFailure)
+}
+
+// A list of file names, for repeated flags.
+type fileList []string
+func (f *fileList) String() string { return strings.Join(*f,",") }
+func (f *fileList) Set(s string) error { *f = append(*f,s); return nil }
+
+// Parses the flags and returns the optional FILE argument.
+func parse(fs *flag.FlagSet, args []string, maxArgs int) (string,error) {
+	fs.SetOutput(io.Discard)
+	e := fs.Parse(args)
+	if e==flag.ErrHelp { return "",usageError(strings.TrimSpace(usage)) }
+	if e!=nil { return "",usageError(e.Error()) }
+	if fs.NArg()>maxArgs { return "",usageError("too many arguments") }
+	return fs.Arg(0),nil
+}
+
+func openInput(name string) (io.ReadCloser,error) {
+	if name=="" || name=="-" { return io.NopCloser(os.Stdin),nil }
+	return os.Open(name)
+}
+
+// Opens the output. Files holding private keys are only readable by the owner.
+func openOutput(name string, private bool) (io.WriteCloser,error) {
+	if name=="" || name=="-" { return os.Stdout,nil }
+	mode := os.FileMode(0644)
+	if private { mode = 0600 }
+	return os.OpenFile(name,os.O_WRONLY|os.O_CREATE|os.O_TRUNC,mode)
+}
+
+func writeOutput(name string, private bool, b []byte) error {
+	f,e := openOutput(name,private)
+	if e!=nil { return e }
+	_,e = f.Write(b)
+	if e2 := f.Close(); e==nil { e = e2 }
+	return e
+}
+
+func readPassphrase(name string) ([]byte,error) {
+	if name=="" { return nil,nil }
+	b,e := os.ReadFile(name)
+	if e!=nil { return nil,e }
+	return bytes.TrimRight(b,"\r\n"),nil
+}
+
+func readPEM(name string) (*pem.Block,[]byte,error) {
+	if name=="" { return nil,nil,usageError("missing key file") }
+	b,e := os.ReadFile(name)
+	if e!=nil { return nil,nil,e }
+	blk,_ := pem.Decode(b)
+	if blk==nil { return nil,nil,fmt.Errorf("%s: no PEM data",name) }
+	return blk,b,nil
+}
+
+func readPrivateKey(name, passFile string) (*gcs.PrivateKey,error) {
+	blk,b,e := readPEM(name)
+	if e!=nil { return nil,e }
+	var priv *gcs.PrivateKey
+	switch blk.Type {
+	case "PRIVATE KEY":
+		priv,e = gcs.ParsePKCS8PrivateKey(blk.Bytes)
+	case pemPrivateKey:
+		priv = new(gcs.PrivateKey)
+		e = priv.UnmarshalBinary(blk.Bytes)
+	case "GCS ENCRYPTED PRIVATE KEY":
+		if passFile=="" { return nil,usageError(name+": the key is encrypted, use --passphrase-file") }
+		pass,e := readPassphrase(passFile)
+		if e!=nil { return nil,e }
+		priv,e = gcs.ParseEncryptedPrivateKey(b,pass)
+		if e!=nil { return nil,fmt.Errorf("%s: %v",name,e) }
+	default:
+		return nil,fmt.Errorf("%s: not a private key (%s)",name,blk.Type)
+	}
+	if e!=nil { return nil,fmt.Errorf("%s: %v",name,e) }
+	return priv,nil
+}
+
+func readPublicKey(name string) (*gcs.PublicKey,error) {
+	blk,_,e := readPEM(name)
+	if e!=nil { return nil,e }
+	var pub *gcs.PublicKey
+	switch blk.Type {
+	case "PUBLIC KEY":
+		pub,e = gcs.ParsePKIXPublicKey(blk.Bytes)
+	case pemPublicKey:
+		pub = new(gcs.PublicKey)
+		e = pub.UnmarshalBinary(blk.Bytes)
+		if e==nil { e = pub.Validate() }
+	default:
+		return nil,fmt.Errorf("%s: not a public key (%s)",name,blk.Type)
+	}
+	if e!=nil { return nil,fmt.Errorf("%s: %v",name,e) }
+	return pub,nil
+}
+
+// Encodes the private key in PKCS #8, if the group has a standard encoding.
+func marshalPrivateKey(priv *gcs.PrivateKey, pass []byte) ([]byte,error) {
+	if pass!=nil { return gcs.MarshalEncryptedPrivateKey(priv,pass,rand.Reader) }
+	b,e := gcs.MarshalPrivateKeyPEM(priv)
+	if !errors.Is(e,gcs.EUnsupportedGroup) { return b,e }
+	der,e := priv.MarshalBinary()
+	if e!=nil { return nil,e }
+	return pem.EncodeToMemory(&pem.Block{Type:pemPrivateKey,Bytes:der}),nil
+}
+
+// Encodes the public key in PKIX, if the group has a standard encoding.
+func marshalPublicKey(pub *gcs.PublicKey) ([]byte,error) {
+	b,e := gcs.MarshalPublicKeyPEM(pub)
+	if !errors.Is(e,gcs.EUnsupportedGroup) { return b,e }
+	der,e := pub.MarshalBinary()
+	if e!=nil { return nil,e }
+	return pem.EncodeToMemory(&pem.Block{Type:pemPublicKey,Bytes:der}),nil
+}
+
+func keygen(args []string) error {
+	fs := flag.NewFlagSet("keygen",flag.ContinueOnError)
+	group := fs.String("group","","the group of the key, see 'gcs groups'")
+	passFile := fs.String("passphrase-file","","encrypt the key with the passphrase in this file")
+	out := fs.String("o","","output file")
+	if _,e := parse(fs,args,0); e!=nil { return e }
+	if *group=="" { return usageError("missing --group") }
+	id := gcs.GroupByName(*group)
+	if id==nil { return usageError("unknown group "+*group) }
+	var pass []byte
+	if *passFile!="" {
+		var e error
+		pass,e = readPassphrase(*passFile)
+		if e!=nil { return e }
+		if pass==nil { pass = []byte{} }
+	}
+	_,priv,e := gcs.GenerateKeyPair(id,rand.Reader)
+	if e!=nil { return e }
+	b,e := marshalPrivateKey(priv,pass)
+	if e!=nil { return e }
+	return writeOutput(*out,true,b)
+}
+
+func pubkey(args []string) error {
+	fs := flag.NewFlagSet("pubkey",flag.ContinueOnError)
+	key := fs.String("k","","private key file")
+	passFile := fs.String("passphrase-file","","file with the passphrase of the key")
+	out := fs.String("o","","output file")
+	if _,e := parse(fs,args,0); e!=nil { return e }
+	priv,e := readPrivateKey(*key,*passFile)
+	if e!=nil { return e }
+	pub := priv.PublicKey()
+	if pub==nil { return gcs.EInvalidGroup }
+	b,e := marshalPublicKey(pub)
+	if e!=nil { return e }
+	return writeOutput(*out,false,b)
+}
+
+func encrypt(args []string) error {
+	var rcpts fileList
+	fs := flag.NewFlagSet("encrypt",flag.ContinueOnError)
+	fs.Var(&rcpts,"r","public key file of a recipient, can be repeated")
+	suite := fs.String("suite","","cipher suite, ChaCha20-Poly1305 by default")
+	armor := fs.Bool("a",false,"armor the output")
+	out := fs.String("o","","output file")
+	in,e := parse(fs,args,1)
+	if e!=nil { return e }
+	if len(rcpts)==0 { return usageError("missing -r") }
+	opts := new(gcs.EncryptOptions)
+	if *suite!="" {
+		ok := false
+		for s := gcs.Suite(0); s.Valid(); s++ {
+			if strings.EqualFold(s.String(),*suite) { opts.Suite,ok = s,true }
+		}
+		if !ok { return usageError("unknown suite "+*suite) }
+	}
+	var pubs []*gcs.PublicKey
+	for _,name := range rcpts {
+		pub,e := readPublicKey(name)
+		if e!=nil { return e }
+		pubs = append(pubs,pub)
+	}
+	src,e := openInput(in)
+	if e!=nil { return e }
+	defer src.Close()
+	f,e := openOutput(*out,false)
+	if e!=nil { return e }
+	dest := f
+	if *armor { dest = gcs.NewArmorWriter(f,gcs.ArmorMessage) }
+	w,e := gcs.EncryptWithOptions(pubs,opts,rand.Reader,dest)
+	if e!=nil { f.Close(); return e }
+	_,e = io.Copy(w,src)
+	if e2 := w.Close(); e==nil { e = e2 }
+	return e
+}
+
+func decrypt(args []string) error {
+	fs := flag.NewFlagSet("decrypt",flag.ContinueOnError)
+	key := fs.String("k","","private key file")
+	passFile := fs.String("passphrase-file","","file with the passphrase of the key")
+	armor := fs.Bool("a",false,"read an armored ciphertext")
+	legacy := fs.Bool("legacy",false,"read the older, unauthenticated format")
+	out := fs.String("o","","output file")
+	in,e := parse(fs,args,1)
+	if e!=nil { return e }
+	if *armor && *legacy { return usageError("-a and --legacy exclude each other") }
+	priv,e := readPrivateKey(*key,*passFile)
+	if e!=nil { return e }
+	src,e := openInput(in)
+	if e!=nil { return e }
+	defer src.Close()
+	var ct io.Reader = src
+	if *armor { ct = gcs.NewArmorReader(src,gcs.ArmorMessage) }
+	open := gcs.Decrypt
+	if *legacy { open = gcs.DecryptLegacy }
+	r,e := open(priv,ct)
+	if e!=nil { return e }
+	if *out=="" || *out=="-" {
+		_,e = io.Copy(os.Stdout,r)
+		return e
+	}
+	
+	/* Written to a temporary file, so a failure leaves no partial plaintext. */
+	tmp,e := os.CreateTemp(filepath.Dir(*out),"."+filepath.Base(*out)+".*")
+	if e!=nil { return e }
+	_,e = io.Copy(tmp,r)
+	if e==nil { e = tmp.Chmod(0644) }
+	if e2 := tmp.Close(); e==nil { e = e2 }
+	if e==nil { e = os.Rename(tmp.Name(),*out) }
+	if e!=nil { os.Remove(tmp.Name()) }
+	return e
+}
+
+func sign(args []string) error {
+	fs := flag.NewFlagSet("sign",flag.ContinueOnError)
+	key := fs.String("k","","private key file")
+	passFile := fs.String("passphrase-file","","file with the passphrase of the key")
+	out := fs.String("o","","output file for the signature")
+	manifest := fs.Bool("manifest",false,"bind the signature to the name and size of the file")
+	context := fs.String("context","","context of a manifest signature")
+	in,e := parse(fs,args,1)
+	if e!=nil { return e }
+	if *manifest && in=="" { return usageError("--manifest needs a file") }
+	priv,e := readPrivateKey(*key,*passFile)
+	if e!=nil { return e }
+	if *manifest {
+		b,e := gcs.SignFile(priv,in,*context,rand.Reader)
+		if e!=nil { return e }
+		return writeOutput(*out,false,b)
+	}
+	src,e := openInput(in)
+	if e!=nil { return e }
+	defer src.Close()
+	s,e := gcs.Sign(priv,rand.Reader)
+	if e!=nil { return e }
+	if _,e = io.Copy(s,src); e!=nil { return e }
+	sig := s.Sign()
+	if sig==nil { return gcs.EInvalidGroup }
+	b,e := sig.Armor()
+	if e!=nil { return e }
+	return writeOutput(*out,false,b)
+}
+
+func verify(args []string) error {
+	fs := flag.NewFlagSet("verify",flag.ContinueOnError)
+	key := fs.String("p","","public key file")
+	sigFile := fs.String("s","","signature file")
+	manifest := fs.Bool("manifest",false,"verify a manifest signature")
+	context := fs.String("context","","context of a manifest signature")
+	in,e := parse(fs,args,1)
+	if e!=nil { return e }
+	if *sigFile=="" { return usageError("missing -s") }
+	if *manifest && in=="" { return usageError("--manifest needs a file") }
+	pub,e := readPublicKey(*key)
+	if e!=nil { return e }
+	b,e := os.ReadFile(*sigFile)
+	if e!=nil { return e }
+	if *manifest {
+		_,e = gcs.VerifyFile(pub,in,*context,b)
+		if e==gcs.EInvalidSignature { return errBadSignature }
+		return e
+	}
+	sig,e := gcs.ParseArmoredSignature(b)
+	if e!=nil { return fmt.Errorf("%s: %v",*sigFile,e) }
+	src,e := openInput(in)
+	if e!=nil { return e }
+	defer src.Close()
+	v,e := gcs.Verify(pub,sig)
+	if e!=nil { return e }
+	if _,e = io.Copy(v,src); e!=nil { return e }
+	if !v.Verify() { return errBadSignature }
+	return nil
+}
+
+func groups(args []string) error {
+	fs := flag.NewFlagSet("groups",flag.ContinueOnError)
+	if _,e := parse(fs,args,0); e!=nil { return e }
+	for g := gcs.Group(0); g<=gcs.FFDHE8192; g++ {
+		if g.Valid() { fmt.Println(g) }
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+/*
+MIT License
+
+Copyright (c) 2017 Simon Schmidt
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package main
+
+import "os"
+import "bytes"
+import "testing"
+import "os/exec"
+import "path/filepath"
+
+/* The test binary runs main instead of the tests, if GCS_TEST_MAIN is set. */
+func TestMain(m *testing.M) {
+	if os.Getenv("GCS_TEST_MAIN")!="" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+/* Runs gcs with the arguments and returns the standard output and the exit status. */
+func run(t *testing.T, args ...string) ([]byte,int) {
+	cmd := exec.Command(os.Args[0],args...)
+	cmd.Env = append(os.Environ(),"GCS_TEST_MAIN=1")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	e := cmd.Run()
+	if x,ok := e.(*exec.ExitError); ok { return out.Bytes(),x.ExitCode() }
+	if e!=nil { t.Fatal(e) }
+	return out.Bytes(),0
+}
+
+/* Runs gcs and fails, if the exit status is not want. */
+func gcsExit(t *testing.T, want int, args ...string) []byte {
+	out,code := run(t,args...)
+	if code!=want { t.Fatal(args,"exit status",code) }
+	return out
+}
+
+func TestRoundTrip(t *testing.T) {
+	d := t.TempDir()
+	f := func(n string) string { return filepath.Join(d,n) }
+	msg := bytes.Repeat([]byte("message "),10000)
+	if e := os.WriteFile(f("msg"),msg,0644); e!=nil { t.Fatal(e) }
+	for _,g := range []string{"P-256","X25519","modp5"} {
+		gcsExit(t,0,"keygen","--group",g,"-o",f("key"))
+		gcsExit(t,0,"pubkey","-k",f("key"),"-o",f("pub"))
+		for _,armor := range []string{"-a=false","-a"} {
+			gcsExit(t,0,"encrypt","-r",f("pub"),armor,"-o",f("ct"),f("msg"))
+			gcsExit(t,0,"decrypt","-k",f("key"),armor,"-o",f("pt"),f("ct"))
+			if pt,_ := os.ReadFile(f("pt")); !bytes.Equal(pt,msg) { t.Fatal(g,armor,"plaintext differs") }
+			if pt := gcsExit(t,0,"decrypt","-k",f("key"),armor,f("ct")); !bytes.Equal(pt,msg) { t.Fatal(g,armor,"plaintext differs") }
+		}
+		gcsExit(t,0,"sign","-k",f("key"),"-o",f("sig"),f("msg"))
+		gcsExit(t,0,"verify","-p",f("pub"),"-s",f("sig"),f("msg"))
+		gcsExit(t,1,"verify","-p",f("pub"),"-s",f("sig"),f("pub"))
+	}
+}
+
+func TestExitStatus(t *testing.T) {
+	d := t.TempDir()
+	f := func(n string) string { return filepath.Join(d,n) }
+	gcsExit(t,2)
+	gcsExit(t,2,"unknown")
+	gcsExit(t,2,"keygen")
+	gcsExit(t,2,"keygen","--group","unknown")
+	gcsExit(t,2,"keygen","--group","P-256","extra")
+	gcsExit(t,0,"keygen","--group","P-256","-o",f("key"))
+	gcsExit(t,0,"keygen","--group","P-256","-o",f("other"))
+	gcsExit(t,0,"pubkey","-k",f("key"),"-o",f("pub"))
+	gcsExit(t,2,"encrypt",f("pub"))
+	gcsExit(t,2,"encrypt","-r",f("pub"),"--suite","unknown",f("pub"))
+	gcsExit(t,2,"decrypt","-k",f("key"),"-a","--legacy",f("pub"))
+	gcsExit(t,0,"encrypt","-r",f("pub"),"-o",f("ct"),f("pub"))
+	
+	/* A failed decryption leaves an existing -o FILE untouched. */
+	if e := os.WriteFile(f("pt"),[]byte("old"),0644); e!=nil { t.Fatal(e) }
+	gcsExit(t,1,"decrypt","-k",f("other"),"-o",f("pt"),f("ct"))
+	ct,_ := os.ReadFile(f("ct"))
+	ct[len(ct)-1] ^= 1
+	if e := os.WriteFile(f("bad"),ct,0644); e!=nil { t.Fatal(e) }
+	gcsExit(t,1,"decrypt","-k",f("key"),"-o",f("pt"),f("bad"))
+	if pt,_ := os.ReadFile(f("pt")); string(pt)!="old" { t.Fatal("output file changed") }
+	gcsExit(t,1,"decrypt","-k",f("key"),"--legacy",f("ct"))
+	if m,_ := filepath.Glob(f(".pt.*")); len(m)!=0 { t.Fatal("temporary file left",m) }
+	
+	gcsExit(t,0,"sign","-k",f("key"),"-o",f("sig"),f("pub"))
+	gcsExit(t,1,"verify","-p",f("pub"),"-s",f("sig"),f("ct"))
+	gcsExit(t,2,"verify","-p",f("pub"),f("pub"))
+	gcsExit(t,1,"verify","-p",f("pub"),"-s",f("missing"),f("pub"))
+}
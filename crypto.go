@@ -0,0 +1,109 @@
+/*
+MIT License
+
+Copyright (c) 2017 Simon Schmidt
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+
+package generalcryptosystem
+
+import "io"
+import "bytes"
+import "crypto"
+
+/*
+*PrivateKey implements crypto.Signer and crypto.Decrypter, and *PublicKey is
+the crypto.PublicKey returned by Public. Both have an Equal method, as the keys
+of the standard library do.
+
+Sign signs the digest as message, using Sign (the SigCompact scheme, hedged
+with randomness from rand, deterministic if rand is nil). opts only tells what
+the digest is: with opts.HashFunc() == 0 it is the whole message (as with
+ed25519), otherwise the hash of the message, computed by the caller, which must
+have the size of the hash function (EDigestLength otherwise). Either way, the
+signature is over the bytes passed as digest, and the same bytes have to be
+passed to VerifyBinary. The result is the binary form of the Signature (see
+Signature.MarshalBinary).
+
+Decrypt decrypts a complete ciphertext produced by Encrypt (or EncryptMulti)
+held in memory. rand and opts are not used.
+*/
+
+var (
+	_ crypto.Signer    = (*PrivateKey)(nil)
+	_ crypto.Decrypter = (*PrivateKey)(nil)
+)
+
+// Returns the public key as *PublicKey.
+func (priv *PrivateKey) Public() crypto.PublicKey {
+	pub := priv.PublicKey()
+	if pub==nil { return nil }
+	return pub
+}
+
+// Signs the digest, see crypto.Signer.
+func (priv *PrivateKey) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte,error) {
+	if opts!=nil {
+		if h := opts.HashFunc(); h!=0 && len(digest)!=h.Size() { return nil,EDigestLength }
+	}
+	s,e := Sign(priv,rand)
+	if e!=nil { return nil,e }
+	s.Write(digest)
+	sig := s.Sign()
+	if sig==nil { return nil,EInvalidGroup }
+	return sig.MarshalBinary()
+}
+
+// Decrypts the ciphertext msg, see crypto.Decrypter.
+func (priv *PrivateKey) Decrypt(rand io.Reader, msg []byte, opts crypto.DecrypterOpts) ([]byte,error) {
+	r,e := Decrypt(priv,bytes.NewReader(msg))
+	if e!=nil { return nil,e }
+	return io.ReadAll(r)
+}
+
+// Reports, whether priv and x are the same key.
+func (priv *PrivateKey) Equal(x crypto.PrivateKey) bool {
+	o,ok := x.(*PrivateKey)
+	if !ok || priv.Secret==nil || o.Secret==nil { return false }
+	return priv.Group.key()==o.Group.key() && priv.Secret.Cmp(o.Secret)==0
+}
+
+// Reports, whether pub and x are the same key.
+func (pub *PublicKey) Equal(x crypto.PublicKey) bool {
+	o,ok := x.(*PublicKey)
+	if !ok || pub.Group.key()!=o.Group.key() { return false }
+	a,e := encodePublic(pub)
+	if e!=nil { return false }
+	b,e := encodePublic(o)
+	if e!=nil { return false }
+	return bytes.Equal(a,b)
+}
+
+// Verifies the binary form of a signature (see Signature.MarshalBinary) over
+// msg, as produced by PrivateKey.Sign.
+func VerifyBinary(pub *PublicKey, msg, sig []byte) bool {
+	s := new(Signature)
+	if s.UnmarshalBinary(sig)!=nil { return false }
+	v,e := Verify(pub,s)
+	if e!=nil { return false }
+	v.Write(msg)
+	return v.Verify()
+}
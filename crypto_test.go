@@ -0,0 +1,55 @@
+/*
+MIT License
+
+Copyright (c) 2017 Simon Schmidt
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+
+package generalcryptosystem
+
+import "bytes"
+import "crypto"
+import "testing"
+import "crypto/rand"
+import "crypto/sha256"
+
+func TestCryptoInterfaces(t *testing.T) {
+	for _,g := range []Group{Modp5,FIPS_P256,X25519} {
+		pub,priv := testKeys(t,g)
+		var s crypto.Signer = priv
+		if !s.Public().(*PublicKey).Equal(pub) { t.Fatal(g,"public") }
+		d := sha256.Sum256([]byte("hello"))
+		sig,e := s.Sign(rand.Reader,d[:],crypto.SHA256)
+		if e!=nil { t.Fatal(g,e) }
+		if !VerifyBinary(pub,d[:],sig) || VerifyBinary(pub,[]byte("hello"),sig) { t.Fatal(g,"verify") }
+		if _,e = s.Sign(rand.Reader,d[:20],crypto.SHA256); e!=EDigestLength { t.Fatal(g,e) }
+		if _,e = s.Sign(rand.Reader,d[:],nil); e!=nil { t.Fatal(g,e) }
+		s1,_ := s.Sign(nil,[]byte("whole"),crypto.Hash(0))
+		s2,_ := s.Sign(nil,[]byte("whole"),crypto.Hash(0))
+		if !bytes.Equal(s1,s2) || !VerifyBinary(pub,[]byte("whole"),s1) { t.Fatal(g,"deterministic") }
+		
+		var dc crypto.Decrypter = priv
+		pt,e := dc.Decrypt(nil,encryptBytes(t,[]*PublicKey{pub},nil,[]byte("pt")),nil)
+		if e!=nil || string(pt)!="pt" { t.Fatal(g,e) }
+		other,_ := testKeys(t,g)
+		if pub.Equal(other) || !priv.Equal(priv) { t.Fatal(g,"equal") }
+	}
+}
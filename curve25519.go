@@ -0,0 +1,153 @@
+/*
+MIT License
+
+Copyright (c) 2017 Simon Schmidt
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package generalcryptosystem
+
+import "io"
+import "math/big"
+import "crypto/rand"
+import "crypto/sha512"
+import "filippo.io/edwards25519"
+import "filippo.io/edwards25519/field"
+
+/*
+The Curve25519 groups use the prime order subgroup of edwards25519, generated
+by the standard base point. The secret is a scalar in [1,l), the public key is
+kept in the Z field of PublicKey (X and Y are zero). GenerateKeyPair derives
+the secret from a seed, as the standards do, so the keys can be exported.
+
+Ed25519: The public key is the compressed Edwards point (RFC 8032). The secret
+is the clamped first half of SHA-512 of the seed, modulo l.
+
+X25519: The public key is the Montgomery u-coordinate (RFC 7748). The secret is
+the clamped seed modulo l, negated if needed so that the Edwards point of the
+public key has an even x-coordinate, so the point can be recovered from u for
+signatures. The Diffie-Hellman secret is the u-coordinate only.
+
+These keys are used with the Schnorr scheme and the envelope of this package,
+not with the signature scheme of RFC 8032.
+
+Curve448 is not supported, as there is no implementation of its arithmetic
+at hand.
+*/
+
+const (
+	c25519_Ed = 1
+	c25519_X  = 2
+)
+
+// The order l of the base point.
+var c25519Order,_ = new(big.Int).SetString("1000000000000000000000000000000014def9dea2f79cd65812631a5cf5d3ed",16)
+
+// Converts a number into a scalar, reducing it modulo l.
+func c25519Scalar(k *big.Int) *edwards25519.Scalar {
+	b := new(big.Int).Mod(k,c25519Order).FillBytes(make([]byte,32))
+	for i,j := 0,31; i<j; i,j = i+1,j-1 { b[i],b[j] = b[j],b[i] }
+	s,_ := edwards25519.NewScalar().SetCanonicalBytes(b)
+	return s
+}
+
+/* GroupImpl. The elements are of type *edwards25519.Point. */
+
+type c25519Group struct{
+	x bool /* X25519 rather than Ed25519 */
+}
+
+// Negates k, if k*G has an odd x-coordinate. Only for X25519.
+func (g c25519Group) evenX(k *big.Int) *big.Int {
+	/* The sign bit of the compressed point is the parity of x. */
+	if g.x && g.BaseMult(k).(*edwards25519.Point).Bytes()[31]&0x80!=0 { k.Sub(c25519Order,k) }
+	return k
+}
+
+func (g c25519Group) secretFromSeed(seed []byte) *big.Int {
+	var b [32]byte
+	if g.x {
+		copy(b[:],seed)
+	}else{
+		h := sha512.Sum512(seed)
+		copy(b[:],h[:32])
+	}
+	b[0] &= 248
+	b[31] &= 127
+	b[31] |= 64
+	for i,j := 0,31; i<j; i,j = i+1,j-1 { b[i],b[j] = b[j],b[i] }
+	k := new(big.Int).SetBytes(b[:])
+	return g.evenX(k.Mod(k,c25519Order))
+}
+
+func (g c25519Group) Order() *big.Int { return c25519Order }
+func (g c25519Group) GenerateSecret(r io.Reader) (*big.Int,error) {
+	k,e := rand.Int(r,new(big.Int).Sub(c25519Order,big.NewInt(1)))
+	if e!=nil { return nil,e }
+	return g.evenX(k.Add(k,big.NewInt(1))),nil
+}
+func (g c25519Group) BaseMult(k *big.Int) Element {
+	return new(edwards25519.Point).ScalarBaseMult(c25519Scalar(k))
+}
+func (g c25519Group) Mult(k *big.Int, p Element) Element {
+	return new(edwards25519.Point).ScalarMult(c25519Scalar(k),p.(*edwards25519.Point))
+}
+func (g c25519Group) Add(p, q Element) Element {
+	return new(edwards25519.Point).Add(p.(*edwards25519.Point),q.(*edwards25519.Point))
+}
+func (g c25519Group) Encode(p Element) []byte {
+	if g.x { return p.(*edwards25519.Point).BytesMontgomery() }
+	return p.(*edwards25519.Point).Bytes()
+}
+func (g c25519Group) Decode(b []byte) (Element,error) {
+	if len(b)!=32 { return nil,EKeyOutOfRange }
+	if g.x {
+		/* y = (u-1)/(u+1), and the x-coordinate is even. */
+		u,e := new(field.Element).SetBytes(b)
+		if e!=nil { return nil,EKeyOutOfRange }
+		one := new(field.Element).One()
+		n := new(field.Element).Subtract(u,one)
+		d := new(field.Element).Add(u,one)
+		if d.Equal(new(field.Element).Zero())==1 { return nil,ENotOnCurve }
+		y := n.Multiply(n,d.Invert(d))
+		b = y.Bytes()
+	}
+	p,e := new(edwards25519.Point).SetBytes(b)
+	if e!=nil { return nil,ENotOnCurve }
+	return p,nil
+}
+func (g c25519Group) KeyElement(pub *PublicKey) (Element,error) {
+	return g.Decode(pub.Z)
+}
+func (g c25519Group) SetKeyElement(pub *PublicKey, p Element) {
+	pub.X = new(big.Int)
+	pub.Y = new(big.Int)
+	pub.Z = g.Encode(p)
+}
+// Rejects the identity and points with a torsion component: l*P must be the
+// identity, computed as (l-1)*P + P, as l is not a canonical scalar.
+func (g c25519Group) Validate(p Element) error {
+	P := p.(*edwards25519.Point)
+	id := edwards25519.NewIdentityPoint()
+	if P.Equal(id)==1 { return EIdentityElement }
+	lP := new(edwards25519.Point).ScalarMult(c25519Scalar(new(big.Int).Sub(c25519Order,big.NewInt(1))),P)
+	if lP.Add(lP,P).Equal(id)!=1 { return ESmallSubgroup }
+	return nil
+}
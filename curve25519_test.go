@@ -0,0 +1,48 @@
+/*
+MIT License
+
+Copyright (c) 2017 Simon Schmidt
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+
+package generalcryptosystem
+
+import "bytes"
+import "testing"
+import "crypto/ecdh"
+import "crypto/rand"
+
+/* The X25519 Diffie-Hellman secret matches RFC 7748, as implemented by crypto/ecdh. */
+func TestX25519ECDH(t *testing.T) {
+	pub,priv := testKeys(t,X25519)
+	g := getGroupImpl(priv.Group)
+	for i := 0; i<8; i++ {
+		k,e := ecdh.X25519().GenerateKey(rand.Reader)
+		if e!=nil { t.Fatal(e) }
+		rk,e := ecdh.X25519().NewPublicKey(pub.Z)
+		if e!=nil { t.Fatal(e) }
+		want,e := k.ECDH(rk)
+		if e!=nil { t.Fatal(e) }
+		P,e := g.Decode(k.PublicKey().Bytes())
+		if e!=nil { t.Fatal(e) }
+		if !bytes.Equal(g.Encode(g.Mult(priv.Secret,P)),want) { t.Fatal(i) }
+	}
+}
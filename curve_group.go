@@ -0,0 +1,114 @@
+/*
+MIT License
+
+Copyright (c) 2017 Simon Schmidt
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package generalcryptosystem
+
+import "io"
+import "math/big"
+import "crypto/elliptic"
+
+// A point on an elliptic.Curve.
+type ecPoint struct{
+	X,Y *big.Int
+}
+
+// GroupImpl for an elliptic.Curve. Also used for the complex groups, which
+// provide the elliptic.Curve interface.
+type curveGroup struct{
+	curve elliptic.Curve
+	order *big.Int
+	
+	/*
+	Check the order of points, as the curve might have a cofactor. Not needed
+	for the built-in curves, which all have a prime order.
+	*/
+	cofactor bool
+}
+
+func (g *curveGroup) Order() *big.Int { return g.order }
+func (g *curveGroup) GenerateSecret(r io.Reader) (*big.Int,error) {
+	priv,_,_,e := elliptic.GenerateKey(g.curve,r)
+	if e!=nil { return nil,e }
+	return new(big.Int).SetBytes(priv),nil
+}
+func (g *curveGroup) BaseMult(k *big.Int) Element {
+	x,y := g.curve.ScalarBaseMult(k.Bytes())
+	return &ecPoint{x,y}
+}
+func (g *curveGroup) Mult(k *big.Int, p Element) Element {
+	P := p.(*ecPoint)
+	x,y := g.curve.ScalarMult(P.X,P.Y,k.Bytes())
+	return &ecPoint{x,y}
+}
+func (g *curveGroup) Add(p, q Element) Element {
+	P,Q := p.(*ecPoint),q.(*ecPoint)
+	x,y := g.curve.Add(P.X,P.Y,Q.X,Q.Y)
+	return &ecPoint{x,y}
+}
+func (g *curveGroup) size() int { return (g.curve.Params().P.BitLen()+7)/8 }
+func (g *curveGroup) Encode(p Element) []byte {
+	P := p.(*ecPoint)
+	l := g.size()
+	b := make([]byte,2*l)
+	P.X.FillBytes(b[:l])
+	P.Y.FillBytes(b[l:])
+	return b
+}
+func (g *curveGroup) Decode(b []byte) (Element,error) {
+	l := g.size()
+	if len(b)!=2*l { return nil,EInvalidGroup }
+	return &ecPoint{new(big.Int).SetBytes(b[:l]),new(big.Int).SetBytes(b[l:])},nil
+}
+func (g *curveGroup) KeyElement(pub *PublicKey) (Element,error) {
+	if pub.X==nil || pub.Y==nil { return nil,EInvalidKey }
+	return &ecPoint{pub.X,pub.Y},nil
+}
+func (g *curveGroup) SetKeyElement(pub *PublicKey, p Element) {
+	P := p.(*ecPoint)
+	pub.X = P.X
+	pub.Y = P.Y
+	pub.Z = []byte{}
+}
+func (g *curveGroup) equal(P *ecPoint, x, y *big.Int) bool {
+	return P.X.Cmp(x)==0 && P.Y.Cmp(y)==0
+}
+func (g *curveGroup) Validate(p Element) error {
+	P := p.(*ecPoint)
+	m := g.curve.Params().P
+	if P.X.Sign()<0 || P.Y.Sign()<0 || P.X.Cmp(m)>=0 || P.Y.Cmp(m)>=0 { return EKeyOutOfRange }
+	/*
+	(0,0) is the point at infinity of the curves, and the zero of the complex
+	groups. The identity of the complex groups is (1,0).
+	*/
+	zero := new(big.Int)
+	if g.equal(P,zero,zero) { return EIdentityElement }
+	ix,iy := g.curve.ScalarBaseMult(nil)
+	if g.equal(P,ix,iy) { return EIdentityElement }
+	if !g.curve.IsOnCurve(P.X,P.Y) { return ENotOnCurve }
+	if g.cofactor {
+		x,y := g.curve.ScalarMult(P.X,P.Y,g.order.Bytes())
+		if !g.equal(&ecPoint{x,y},ix,iy) { return ESmallSubgroup }
+	}
+	return nil
+}
@@ -29,9 +29,13 @@ This Package implements a fully integrated Public Key System. Every Keypair can
 be used for both, signatures and encryption. The Encryption is done using a simple
 Diffie-Hellman-Scheme with symetric cipher. The signature scheme is based on
 Schnorr's signature (see https://en.wikipedia.org/wiki/Schnorr_signature ).
+Besides the ModP groups and the curves of crypto/elliptic, Curve25519 and
+ristretto255 are supported, and further groups can be registered at runtime.
 
-For Encryption, the cipher Twofish ist used in 256-bit mode. For Hashing
-(Schnorr signature) BLAKE2b is used, where BLAKE2b is used as keyed MAC.
+For Encryption, the data is split into segments, each of them sealed with an
+AEAD cipher (ChaCha20-Poly1305 by default), so that any modification of the
+ciphertext is detected by Decrypt. For Hashing (Schnorr signature) BLAKE2b is
+used, where BLAKE2b is used as keyed MAC.
 */
 package generalcryptosystem
 
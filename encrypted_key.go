@@ -0,0 +1,153 @@
+/*
+MIT License
+
+Copyright (c) 2017 Simon Schmidt
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+
+package generalcryptosystem
+
+import "io"
+import "encoding/asn1"
+import "encoding/pem"
+import "golang.org/x/crypto/scrypt"
+import "golang.org/x/crypto/chacha20poly1305"
+
+/*
+An encrypted private key is a PEM block of the type pemEncryptedKey, holding
+
+	SEQUENCE {
+		header SEQUENCE {
+			version INTEGER (1)
+			group   the group of the key (see oids.go)
+			salt    OCTET STRING
+			n, r, p INTEGER (the scrypt cost parameters)
+			nonce   OCTET STRING
+		}
+		sealed OCTET STRING
+	}
+
+The key is derived from the passphrase with scrypt (RFC 7914). sealed is the
+output of PrivateKey.MarshalBinary, sealed with XChaCha20-Poly1305 under this
+key. The header is passed as additional data, so it can not be changed either.
+*/
+
+const pemEncryptedKey = "GCS ENCRYPTED PRIVATE KEY"
+
+const encryptedKeyVersion = 1
+
+// The scrypt cost parameters for MarshalEncryptedPrivateKeyWithParams.
+type ScryptParams struct{
+	N int /* CPU and memory cost, a power of two */
+	R int /* Block size */
+	P int /* Parallelization */
+}
+
+var defaultScryptParams = ScryptParams{1<<15,8,1}
+
+/*
+The highest memory use (128*N*r bytes) accepted by ParseEncryptedPrivateKey,
+so that a crafted file can not exhaust the memory.
+*/
+const scryptMaxMemory = 1<<30
+
+type encryptedKeyHeader struct{
+	Version int
+	Group   asn1.RawValue
+	Salt    []byte
+	N,R,P   int
+	Nonce   []byte
+}
+
+type encryptedKey struct{
+	Header asn1.RawValue
+	Sealed []byte
+}
+
+func (s *ScryptParams) valid() bool {
+	if s.N<=1 || s.N&(s.N-1)!=0 || s.R<1 || s.P<1 || s.P>16 { return false }
+	return s.N <= scryptMaxMemory/128/s.R
+}
+
+// Encrypts the private key with the passphrase, using the default scrypt
+// parameters (N=32768, r=8, p=1). The result is PEM encoded. The salt and the
+// nonce are read from r.
+func MarshalEncryptedPrivateKey(priv *PrivateKey, passphrase []byte, r io.Reader) ([]byte,error) {
+	return MarshalEncryptedPrivateKeyWithParams(priv,passphrase,nil,r)
+}
+
+// Like MarshalEncryptedPrivateKey, but with the given scrypt parameters. A nil
+// *ScryptParams selects the defaults.
+func MarshalEncryptedPrivateKeyWithParams(priv *PrivateKey, passphrase []byte, params *ScryptParams, r io.Reader) ([]byte,error) {
+	if params==nil { params = &defaultScryptParams }
+	if !params.valid() { return nil,EInvalidKDFParams }
+	if getGroupImpl(priv.Group)==nil { return nil,EInvalidGroup }
+	if priv.Secret==nil { return nil,EInvalidKey }
+	plain,e := priv.MarshalBinary()
+	if e!=nil { return nil,e }
+	gid,e := marshalGroupID(priv.Group)
+	if e!=nil { return nil,e }
+	h := encryptedKeyHeader{encryptedKeyVersion,gid,make([]byte,32),params.N,params.R,params.P,make([]byte,chacha20poly1305.NonceSizeX)}
+	_,e = io.ReadFull(r,h.Salt)
+	if e!=nil { return nil,e }
+	_,e = io.ReadFull(r,h.Nonce)
+	if e!=nil { return nil,e }
+	hb,e := asn1.Marshal(h)
+	if e!=nil { return nil,e }
+	key,e := scrypt.Key(passphrase,h.Salt,h.N,h.R,h.P,chacha20poly1305.KeySize)
+	if e!=nil { return nil,e }
+	aead,e := chacha20poly1305.NewX(key)
+	if e!=nil { return nil,e }
+	b,e := asn1.Marshal(encryptedKey{asn1.RawValue{FullBytes:hb},aead.Seal(nil,h.Nonce,plain,hb)})
+	if e!=nil { return nil,e }
+	return pem.EncodeToMemory(&pem.Block{Type:pemEncryptedKey,Bytes:b}),nil
+}
+
+// Decrypts the first encrypted private key in data. Fails with
+// EWrongPassphrase, if the passphrase is wrong or the key was modified.
+func ParseEncryptedPrivateKey(data, passphrase []byte) (*PrivateKey,error) {
+	der,e := decodePEM(data,pemEncryptedKey)
+	if e!=nil { return nil,e }
+	var ek encryptedKey
+	rest,e := asn1.Unmarshal(der,&ek)
+	if e!=nil || len(rest)!=0 { return nil,EInvalidEncoding }
+	var h encryptedKeyHeader
+	rest,e = asn1.Unmarshal(ek.Header.FullBytes,&h)
+	if e!=nil || len(rest)!=0 { return nil,EInvalidEncoding }
+	if h.Version!=encryptedKeyVersion { return nil,EUnsupportedVersion }
+	if !(&ScryptParams{h.N,h.R,h.P}).valid() { return nil,EInvalidKDFParams }
+	if len(h.Nonce)!=chacha20poly1305.NonceSizeX { return nil,EInvalidEncoding }
+	group,e := parseGroupID(h.Group)
+	if e!=nil { return nil,e }
+	
+	key,e := scrypt.Key(passphrase,h.Salt,h.N,h.R,h.P,chacha20poly1305.KeySize)
+	if e!=nil { return nil,e }
+	aead,e := chacha20poly1305.NewX(key)
+	if e!=nil { return nil,e }
+	plain,e := aead.Open(nil,h.Nonce,ek.Sealed,ek.Header.FullBytes)
+	if e!=nil { return nil,EWrongPassphrase }
+	
+	priv := new(PrivateKey)
+	e = priv.UnmarshalBinary(plain)
+	if e!=nil { return nil,e }
+	if priv.Group.key()!=group.key() { return nil,EGroupMismatch }
+	return priv,nil
+}
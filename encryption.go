@@ -26,99 +26,62 @@ package generalcryptosystem
 
 import "io"
 import "bytes"
-import "math/big"
-import "crypto/elliptic"
 import "crypto/cipher"
-import "crypto/rand"
 import "golang.org/x/crypto/blake2b"
 import "golang.org/x/crypto/twofish"
 import "encoding/asn1"
 import "encoding/binary"
 
-type encrypter struct{
-	dest io.Writer
-	clos io.Closer
-	buf bytes.Buffer
-	mode cipher.BlockMode
-	fb []byte
-}
-func (e *encrypter) get(i int) []byte {
-	if cap(e.fb) < i { e.fb = make([]byte,0,i) }
-	return e.fb[:i]
-}
-func (e *encrypter) Write(p []byte) (n int, err error){
-	n,err = e.buf.Write(p)
-	e.push()
-	return
-}
-func (e *encrypter) Close() error {
-	e.push()
-	pad := padBlock(e.buf.Len(),e.mode.BlockSize())
-	e.buf.Write(pad)
-	dest := e.get(e.buf.Len())
-	e.mode.CryptBlocks(dest,e.buf.Bytes())
-	e.dest.Write(dest)
-	if e.clos==nil { return nil }
-	return e.clos.Close()
-}
-func (e *encrypter) push(){
-	bz := e.mode.BlockSize()
-	l := e.buf.Len()
-	l -= l%bz
-	if l==0 { return }
-	dest := e.get(l)
-	e.mode.CryptBlocks(dest,e.buf.Next(l))
-	e.dest.Write(dest)
-}
-func Encrypt(pub *PublicKey, r io.Reader, dest io.Writer) (io.WriteCloser,error) {
+// Generates an ephemeral key in the group of pub and computes the shared
+// Diffie-Hellman element. Returns the public part of the ephemeral key
+// together with the element.
+func ephemeralSecret(pub *PublicKey, r io.Reader) (*PublicKey,GroupImpl,Element,error) {
+	g := getGroupImpl(pub.Group)
+	if g==nil { return nil,nil,nil,EInvalidGroup }
+	e := pub.Validate()
+	if e!=nil { return nil,nil,nil,e }
+	P,_ := g.KeyElement(pub)
+	t,e := g.GenerateSecret(r)
+	if e!=nil { return nil,nil,nil,e }
 	peer := new(PublicKey)
 	peer.Group = pub.Group
-	var K []byte
-	if pub.Group[0]==group_ModP {
-		g,ok := linearGroups[pub.Group[1]]
-		if !ok { return nil,EInvalidGroup }
-		t,T,e := modpKey(pub.Group[1],r)
-		if e!=nil { return nil,e }
-		peer.X = T
-		peer.Y = new(big.Int).SetUint64(0)
-		peer.Z = []byte{}
-		Ke := new(big.Int).Exp(pub.X,t,g.P)
-		K = Ke.Bytes()
-	}else  if curve := getCurve(pub.Group); curve!=nil {
-		var Secret []byte
-		var e error
-		Secret,peer.X,peer.Y,e = elliptic.GenerateKey(curve,r)
-		if e!=nil { return nil,e }
-		peer.Z = []byte{}
-		x,y := curve.ScalarMult(pub.X,pub.Y,Secret)
-		K = append(x.Bytes(),y.Bytes()...)
-	}else { return nil,EInvalidGroup }
-	
-	var iv [16]byte
-	key := blake2b.Sum256(K)
-	c,_ := twofish.NewCipher(key[:])
-	rand.Read(iv[:])
-	mode := cipher.NewCBCEncrypter(c,iv[:])
-	cl,ok := dest.(io.Closer)
-	if !ok { cl=nil }
-	
-	b,e := asn1.Marshal(*peer)
-	if e!=nil { return nil,e }
+	g.SetKeyElement(peer,g.BaseMult(t))
+	return peer,g,g.Mult(t,P),nil
+}
+
+// Computes the shared Diffie-Hellman element from the private key and the
+// ephemeral public key of the sender.
+func sharedSecret(priv *PrivateKey, peer *PublicKey) (GroupImpl,Element,error) {
+	if len(peer.Group)!=len(priv.Group) { return nil,nil,EGroupMismatch }
+	for i,grp := range peer.Group {
+		if priv.Group[i]!=grp { return nil,nil,EGroupMismatch }
+	}
 	
-	var bl uint32
-	bl = uint32(len(b))
-	e = binary.Write(dest,binary.BigEndian,bl)
-	if e!=nil { return nil,e }
-	_,e = dest.Write(b)
-	if e!=nil { return nil,e }
-	_,e = dest.Write(iv[:])
+	g := getGroupImpl(priv.Group)
+	if g==nil { return nil,nil,EInvalidGroup }
+	e := peer.Validate()
+	if e!=nil { return nil,nil,e }
+	P,_ := g.KeyElement(peer)
+	return g,g.Mult(priv.Secret,P),nil
+}
+
+// Encodes the element of a public key with the fixed length of the group.
+// The key must have been validated.
+func encodePublic(pub *PublicKey) ([]byte,error) {
+	g := getGroupImpl(pub.Group)
+	if g==nil { return nil,EInvalidGroup }
+	P,e := g.KeyElement(pub)
 	if e!=nil { return nil,e }
-	
-	enc := new(encrypter)
-	enc.dest = dest
-	enc.clos = cl
-	enc.mode = mode
-	return enc,nil
+	return g.Encode(P),nil
+}
+
+// Encrypts the data written to the returned io.WriteCloser for the owner of
+// pub and writes the ciphertext to dest. The output is an authenticated
+// envelope (see envelope.go) with a single recipient. The Close method must be
+// called to flush the last segment. If dest is an io.Closer, it will be closed
+// as well.
+func Encrypt(pub *PublicKey, r io.Reader, dest io.Writer) (io.WriteCloser,error) {
+	return EncryptMulti([]*PublicKey{pub},r,dest)
 }
 
 type decrypter struct{
@@ -183,15 +146,34 @@ func (d *decrypter) Read(p []byte) (n int, err error) {
 	return
 }
 
-
+// Decrypts a ciphertext produced by Encrypt. Input without the magic bytes of
+// the envelope, like the older Twofish-CBC format, is rejected with
+// EUnsupportedVersion (see DecryptLegacy).
 func Decrypt(priv *PrivateKey, src io.Reader) (io.Reader,error) {
-	var hl uint32
-	var iv [16]byte
-	e := binary.Read(src,binary.BigEndian,&hl)
+	var magic [4]byte
+	_,e := io.ReadFull(src,magic[:])
+	if e!=nil { return nil,e }
+	if magic!=envelopeMagic { return nil,EUnsupportedVersion }
+	return readEnvelope(priv,src)
+}
+
+// Decrypts a ciphertext of the older, unauthenticated Twofish-CBC format, as
+// written before the envelope. This format offers no integrity protection: a
+// modified ciphertext decrypts to modified plaintext without an error. For an
+// envelope, EUnsupportedVersion is returned.
+func DecryptLegacy(priv *PrivateKey, src io.Reader) (io.Reader,error) {
+	var pre [4]byte
+	_,e := io.ReadFull(src,pre[:])
 	if e!=nil { return nil,e }
+	if pre==envelopeMagic { return nil,EUnsupportedVersion }
+	return decryptLegacy(priv,binary.BigEndian.Uint32(pre[:]),src)
+}
+
+func decryptLegacy(priv *PrivateKey, hl uint32, src io.Reader) (io.Reader,error) {
+	var iv [16]byte
 	if hl > (1<<20) { return nil,EHeaderTooBig }
 	b := make([]byte,int(hl))
-	_,e = io.ReadFull(src,b)
+	_,e := io.ReadFull(src,b)
 	if e!=nil { return nil,e }
 	_,e = io.ReadFull(src,iv[:])
 	if e!=nil { return nil,e }
@@ -199,34 +181,15 @@ func Decrypt(priv *PrivateKey, src io.Reader) (io.Reader,error) {
 	_,e = asn1.Unmarshal(b,peer)
 	if e!=nil { return nil,e }
 	
-	if len(peer.Group)!=len(priv.Group) { return nil,EGroupMismatch }
-	for i,grp := range peer.Group {
-		if priv.Group[i]!=grp { return nil,EGroupMismatch }
-	}
-	
-	var K []byte
-	if len(priv.Group)<2 { return nil,EInvalidGroup }
-	if priv.Group[0]==group_ModP {
-		g,ok := linearGroups[priv.Group[1]]
-		if !ok { return nil,EInvalidGroup }
-		Ke := new(big.Int).Exp(peer.X,priv.Secret,g.P)
-		K = Ke.Bytes()
-	}else if curve := getCurve(priv.Group); curve!=nil {
-		x,y := curve.ScalarMult(peer.X,peer.Y,priv.Secret.Bytes())
-		K = append(x.Bytes(),y.Bytes()...)
-	}else { return nil,EInvalidGroup }
-	
+	g,K,e := sharedSecret(priv,peer)
+	if e!=nil { return nil,e }
 	
-	key := blake2b.Sum256(K)
+	key := blake2b.Sum256(legacyBytes(g,K))
 	c,_ := twofish.NewCipher(key[:])
 	mode := cipher.NewCBCDecrypter(c,iv[:])
 	
 	dec := new(decrypter)
 	
-	//dec.ctb = new(bytes.Buffer)
-	//dec.dec = new(bytes.Buffer)
-	//dec.user = new(bytes.Buffer)
-	
 	dec.src  = src
 	dec.mode = mode
 	dec.fb   = make([]byte,1<<12)
@@ -234,4 +197,3 @@ func Decrypt(priv *PrivateKey, src io.Reader) (io.Reader,error) {
 	return dec,nil
 }
 
-
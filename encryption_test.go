@@ -0,0 +1,109 @@
+/*
+MIT License
+
+Copyright (c) 2017 Simon Schmidt
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+
+package generalcryptosystem
+
+import "io"
+import "os"
+import "bytes"
+import "testing"
+import "crypto/rand"
+import "crypto/cipher"
+import "encoding/asn1"
+import "encoding/binary"
+import "golang.org/x/crypto/blake2b"
+import "golang.org/x/crypto/twofish"
+
+/*
+The files in testdata/baseline were written by the version before the
+authenticated envelope: the keys and the signature as asn1.Marshal of the
+structs, the ciphertext of "legacy message 1234567890" and the signature of
+"signed".
+*/
+var baselineNames = []string{"modp5","modp14","p256"}
+
+func readBaseline(t *testing.T, name string) (*PublicKey,*PrivateKey) {
+	pb,e := os.ReadFile("testdata/baseline/"+name+".pub")
+	if e!=nil { t.Fatal(e) }
+	kb,e := os.ReadFile("testdata/baseline/"+name+".key")
+	if e!=nil { t.Fatal(e) }
+	pub,priv := new(PublicKey),new(PrivateKey)
+	if _,e = asn1.Unmarshal(pb,pub); e!=nil { t.Fatal(name,e) }
+	if _,e = asn1.Unmarshal(kb,priv); e!=nil { t.Fatal(name,e) }
+	return pub,priv
+}
+
+func decryptLegacyBytes(priv *PrivateKey, ct []byte) ([]byte,error) {
+	r,e := DecryptLegacy(priv,bytes.NewReader(ct))
+	if e!=nil { return nil,e }
+	return io.ReadAll(r)
+}
+
+func TestDecryptBaseline(t *testing.T) {
+	for _,name := range baselineNames {
+		pub,priv := readBaseline(t,name)
+		if e := pub.Validate(); e!=nil { t.Fatal(name,e) }
+		if !priv.PublicKey().Equal(pub) { t.Fatal(name,"public key") }
+		ct,e := os.ReadFile("testdata/baseline/"+name+".ct")
+		if e!=nil { t.Fatal(e) }
+		out,e := decryptLegacyBytes(priv,ct)
+		if e!=nil || string(out)!="legacy message 1234567890" { t.Fatal(name,e,out) }
+		if _,e = decryptBytes(priv,ct); e!=EUnsupportedVersion { t.Fatal(name,e) }
+		h,e := ParseHeader(bytes.NewReader(ct))
+		if e!=nil || h.Version!=0 || h.Suite!=LegacyTwofish || !sameGroup(h.Recipients[0],pub.Group) { t.Fatal(name,e,h) }
+	}
+}
+
+/* Writes the legacy format, as Encrypt did before the envelope. */
+func legacyEncrypt(t *testing.T, pub *PublicKey, msg []byte) []byte {
+	peer,g,K,e := ephemeralSecret(pub,rand.Reader)
+	if e!=nil { t.Fatal(e) }
+	key := blake2b.Sum256(legacyBytes(g,K))
+	c,_ := twofish.NewCipher(key[:])
+	iv := make([]byte,16)
+	rand.Read(iv)
+	b,_ := asn1.Marshal(*peer)
+	ps := 16-len(msg)%16
+	pt := append(append([]byte(nil),msg...),bytes.Repeat([]byte{byte(ps)},ps)...)
+	cipher.NewCBCEncrypter(c,iv).CryptBlocks(pt,pt)
+	ct := binary.BigEndian.AppendUint32(nil,uint32(len(b)))
+	ct = append(ct,b...)
+	ct = append(ct,iv...)
+	return append(ct,pt...)
+}
+
+func TestDecryptLegacy(t *testing.T) {
+	for _,g := range []Group{Modp5,FIPS_P256,Koblitz_S256} {
+		pub,priv := testKeys(t,g)
+		for _,n := range []int{0,1,15,16,17,4095,4096,4097,10000} {
+			msg := testMessage(n)
+			out,e := decryptLegacyBytes(priv,legacyEncrypt(t,pub,msg))
+			if e!=nil || !bytes.Equal(out,msg) { t.Fatal(g,n,e) }
+		}
+		/* Neither format is taken for the other. */
+		if _,e := decryptBytes(priv,legacyEncrypt(t,pub,[]byte("x"))); e!=EUnsupportedVersion { t.Fatal(g,e) }
+		if _,e := decryptLegacyBytes(priv,encryptBytes(t,[]*PublicKey{pub},nil,[]byte("x"))); e!=EUnsupportedVersion { t.Fatal(g,e) }
+	}
+}
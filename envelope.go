@@ -0,0 +1,210 @@
+/*
+MIT License
+
+Copyright (c) 2017 Simon Schmidt
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package generalcryptosystem
+
+import "io"
+import "crypto/cipher"
+import "encoding/asn1"
+import "encoding/binary"
+
+/*
+The authenticated envelope written by Encrypt has the following layout:
+
+	magic    4 bytes   "GCSE"
+	version  1 byte
+	length   uint32    (big endian) length of the header
+	header   ASN.1 encoded recipientsHeader
+	payload  ...
+
+The header holds the random nonce, the recipient stanzas, the cipher Suite,
+flags and optional metadata (see header.go). No flags are defined yet, headers
+with any flag set are rejected.
+
+The payload is sealed with the cipher Suite named in the header, using keys
+derived from a random content key. For every recipient, the header holds a
+stanza with an ephemeral public key in the group of the recipient and the
+content key, sealed (using the same Suite) with the key encryption key derived
+from the Diffie-Hellman secret (see kdf.go). Headers without a Suite use
+ChaCha20Poly1305. The envelope prefix (magic, version, length and header) is
+passed as additional data to every piece of the payload, so any change to the
+header causes Decrypt to fail with EAuthFailed. The payload is a segmented
+stream, as described in stream.go.
+
+The groups of the keys in the header are stored as object identifiers, where
+the group has one (see oids.go).
+*/
+
+var envelopeMagic = [4]byte{'G','C','S','E'}
+
+const envelopeVersion = 1
+
+const contentKeySize = 32
+
+type recipientStanza struct{
+	Peer publicKeyData
+	Key  []byte
+}
+
+type recipientsHeader struct{
+	Nonce      []byte
+	Recipients []recipientStanza
+	Suite      int `asn1:"optional,default:0"`
+	Flags      int `asn1:"optional,explicit,tag:0,default:0"`
+	Metadata   []MetadataEntry `asn1:"optional,explicit,tag:1"`
+}
+
+// Options for EncryptWithOptions. A nil *EncryptOptions selects the defaults.
+type EncryptOptions struct{
+	// The cipher suite for the payload. The zero value is ChaCha20Poly1305.
+	Suite Suite
+	
+	// Metadata to be stored in the header. It is not encrypted, but
+	// authenticated, and can be read by ParseHeader without a private key.
+	Metadata []MetadataEntry
+}
+
+// Derives the key encryption key of a recipient from the shared element, the
+// ephemeral key and the key of the recipient.
+func recipientKey(s suiteImpl, g GroupImpl, K Element, eph, rcp *PublicKey, nonce []byte) (cipher.AEAD,error) {
+	shared := g.Encode(K)
+	ek,e := encodePublic(eph)
+	if e!=nil { return nil,e }
+	rk,e := encodePublic(rcp)
+	if e!=nil { return nil,e }
+	gid,e := asn1.Marshal(rcp.Group)
+	if e!=nil { return nil,e }
+	return s.derive(kdf(shared,nonce,kdfLabelRecipient,gid,ek,rk))
+}
+
+// Seals the content key for one recipient. As the key encryption key is
+// derived from a fresh ephemeral key, a constant nonce is used.
+func wrapKey(kek cipher.AEAD, key []byte) []byte {
+	return kek.Seal(nil,make([]byte,kek.NonceSize()),key,nil)
+}
+func unwrapKey(kek cipher.AEAD, wrapped []byte) ([]byte,error) {
+	return kek.Open(nil,make([]byte,kek.NonceSize()),wrapped,nil)
+}
+
+// Encrypts the data written to the returned io.WriteCloser for every key in
+// pubs and writes the ciphertext to dest. The keys might be from different
+// groups. Any of the corresponding private keys can decrypt the output using
+// Decrypt. The Close method must be called to flush the last segment. If dest
+// is an io.Closer, it will be closed as well.
+func EncryptMulti(pubs []*PublicKey, r io.Reader, dest io.Writer) (io.WriteCloser,error) {
+	return EncryptWithOptions(pubs,nil,r,dest)
+}
+
+// Like EncryptMulti, but with the given options.
+func EncryptWithOptions(pubs []*PublicKey, opts *EncryptOptions, r io.Reader, dest io.Writer) (io.WriteCloser,error) {
+	if len(pubs)==0 { return nil,ENoRecipient }
+	if opts==nil { opts = new(EncryptOptions) }
+	s,e := getSuite(opts.Suite)
+	if e!=nil { return nil,e }
+	hdr := recipientsHeader{Nonce:make([]byte,32),Suite:int(opts.Suite),Metadata:opts.Metadata}
+	_,e = io.ReadFull(r,hdr.Nonce)
+	if e!=nil { return nil,e }
+	ck := make([]byte,contentKeySize)
+	_,e = io.ReadFull(r,ck)
+	if e!=nil { return nil,e }
+	
+	for _,pub := range pubs {
+		peer,g,K,e := ephemeralSecret(pub,r)
+		if e!=nil { return nil,e }
+		kek,e := recipientKey(s,g,K,peer,pub,hdr.Nonce)
+		if e!=nil { return nil,e }
+		pd,e := newPublicKeyData(peer)
+		if e!=nil { return nil,e }
+		hdr.Recipients = append(hdr.Recipients,recipientStanza{*pd,wrapKey(kek,ck)})
+	}
+	
+	b,e := asn1.Marshal(hdr)
+	if e!=nil { return nil,e }
+	
+	ad := make([]byte,0,9+len(b))
+	ad = append(ad,envelopeMagic[:]...)
+	ad = append(ad,envelopeVersion)
+	ad = binary.BigEndian.AppendUint32(ad,uint32(len(b)))
+	ad = append(ad,b...)
+	
+	aead,e := s.derive(kdf(ck,hdr.Nonce,kdfLabelPayload))
+	if e!=nil { return nil,e }
+	_,e = dest.Write(ad)
+	if e!=nil { return nil,e }
+	
+	enc := new(segmentWriter)
+	enc.dest = dest
+	enc.clos,_ = dest.(io.Closer)
+	enc.aead = aead
+	enc.ad   = ad
+	enc.buf  = make([]byte,0,segmentSize)
+	return enc,nil
+}
+
+// Finds the stanza of the recipients header, that can be opened using priv,
+// and returns the payload cipher. If none can be opened, the first validation
+// error of an ephemeral key is returned, ENoRecipient otherwise.
+func openRecipients(priv *PrivateKey, hdr *recipientsHeader) (cipher.AEAD,error) {
+	s,e := getSuite(Suite(hdr.Suite))
+	if e!=nil { return nil,e }
+	var pub *PublicKey
+	var invalid error
+	matched := false
+	for i := range hdr.Recipients {
+		st := &hdr.Recipients[i]
+		peer,e := st.Peer.key()
+		if e!=nil { continue }
+		g,K,e := sharedSecret(priv,peer)
+		if e==EGroupMismatch { continue }
+		matched = true
+		if e!=nil {
+			if invalid==nil { invalid = e }
+			continue
+		}
+		if pub==nil { pub = priv.PublicKey() }
+		if pub==nil { return nil,EInvalidGroup }
+		kek,e := recipientKey(s,g,K,peer,pub,hdr.Nonce)
+		if e!=nil { continue }
+		ck,e := unwrapKey(kek,st.Key)
+		if e!=nil { continue }
+		return s.derive(kdf(ck,hdr.Nonce,kdfLabelPayload))
+	}
+	if !matched { return nil,EGroupMismatch }
+	if invalid!=nil { return nil,invalid }
+	return nil,ENoRecipient
+}
+
+// Sets up the payload cipher for the parsed envelope header.
+func openEnvelope(priv *PrivateKey, p *parsedEnvelope) (cipher.AEAD,error) {
+	return openRecipients(priv,p.multi)
+}
+
+// Reads the remainder of the envelope, after the magic bytes.
+func readEnvelope(priv *PrivateKey, src io.Reader) (io.Reader,error) {
+	p,e := parseEnvelope(src)
+	if e!=nil { return nil,e }
+	aead,e := openEnvelope(priv,p)
+	if e!=nil { return nil,e }
+	return newSegmentReader(src,aead,p.ad),nil
+}
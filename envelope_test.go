@@ -0,0 +1,194 @@
+/*
+MIT License
+
+Copyright (c) 2017 Simon Schmidt
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+
+package generalcryptosystem
+
+import "io"
+import "bytes"
+import "testing"
+import "math/big"
+import "crypto/rand"
+import "encoding/asn1"
+import "encoding/binary"
+
+/* One group of every kind. */
+var testGroups = []Group{Modp5,FFDHE2048,FIPS_P256,Koblitz_S256,Brainpool_P256r1,Complex_2048bit,Ed25519,X25519,Ristretto255}
+
+func testKeys(t *testing.T, g Group) (*PublicKey,*PrivateKey) {
+	pub,priv,e := GenerateKeyPair(g.ID(),rand.Reader)
+	if e!=nil { t.Fatal(g,e) }
+	return pub,priv
+}
+
+func testMessage(n int) []byte {
+	msg := make([]byte,n)
+	rand.Read(msg)
+	return msg
+}
+
+func encryptBytes(t *testing.T, pubs []*PublicKey, opts *EncryptOptions, msg []byte) []byte {
+	var buf bytes.Buffer
+	w,e := EncryptWithOptions(pubs,opts,rand.Reader,&buf)
+	if e!=nil { t.Fatal(e) }
+	if _,e = w.Write(msg); e!=nil { t.Fatal(e) }
+	if e = w.Close(); e!=nil { t.Fatal(e) }
+	return buf.Bytes()
+}
+
+func decryptBytes(priv *PrivateKey, ct []byte) ([]byte,error) {
+	r,e := Decrypt(priv,bytes.NewReader(ct))
+	if e!=nil { return nil,e }
+	return io.ReadAll(r)
+}
+
+/* Returns the length of the envelope prefix and header. */
+func headerLength(ct []byte) int {
+	return 9+int(binary.BigEndian.Uint32(ct[5:9]))
+}
+
+/* The plaintext sizes around the segment boundaries. */
+var segmentSizes = []int{0,1,segmentSize-1,segmentSize,segmentSize+1,2*segmentSize,2*segmentSize+1}
+
+func TestEncryptGroups(t *testing.T) {
+	msg := testMessage(1000)
+	for _,g := range testGroups {
+		pub,priv := testKeys(t,g)
+		out,e := decryptBytes(priv,encryptBytes(t,[]*PublicKey{pub},nil,msg))
+		if e!=nil || !bytes.Equal(out,msg) { t.Fatal(g,e) }
+	}
+}
+
+func TestEncryptSizes(t *testing.T) {
+	pub,priv := testKeys(t,FIPS_P256)
+	for _,s := range []Suite{ChaCha20Poly1305,AES256GCM,XChaCha20Poly1305,TwofishCBC} {
+		for _,n := range segmentSizes {
+			msg := testMessage(n)
+			out,e := decryptBytes(priv,encryptBytes(t,[]*PublicKey{pub},&EncryptOptions{Suite:s},msg))
+			if e!=nil || !bytes.Equal(out,msg) { t.Fatal(s,n,e) }
+		}
+	}
+}
+
+/* Writes of any size give the same plaintext. */
+func TestEncryptSmallWrites(t *testing.T) {
+	pub,priv := testKeys(t,FIPS_P256)
+	msg := testMessage(segmentSize+100)
+	var buf bytes.Buffer
+	w,e := Encrypt(pub,rand.Reader,&buf)
+	if e!=nil { t.Fatal(e) }
+	for p := msg; len(p)>0; {
+		n := 1+len(p)%4099
+		if n>len(p) { n = len(p) }
+		w.Write(p[:n])
+		p = p[n:]
+	}
+	w.Close()
+	out,e := decryptBytes(priv,buf.Bytes())
+	if e!=nil || !bytes.Equal(out,msg) { t.Fatal(e) }
+}
+
+func TestEncryptMulti(t *testing.T) {
+	var pubs []*PublicKey
+	var privs []*PrivateKey
+	for _,g := range []Group{FIPS_P256,Modp5,Koblitz_S256,FIPS_P256} {
+		pub,priv := testKeys(t,g)
+		pubs = append(pubs,pub)
+		privs = append(privs,priv)
+	}
+	msg := testMessage(segmentSize+1)
+	ct := encryptBytes(t,pubs,nil,msg)
+	for i,priv := range privs {
+		out,e := decryptBytes(priv,ct)
+		if e!=nil || !bytes.Equal(out,msg) { t.Fatal(i,e) }
+	}
+	_,other := testKeys(t,FIPS_P256)
+	if _,e := decryptBytes(other,ct); e!=ENoRecipient { t.Fatal(e) }
+	_,other = testKeys(t,FIPS_P384)
+	if _,e := decryptBytes(other,ct); e!=EGroupMismatch { t.Fatal(e) }
+	if _,e := EncryptMulti(nil,rand.Reader,io.Discard); e!=ENoRecipient { t.Fatal(e) }
+}
+
+func TestEncryptTruncated(t *testing.T) {
+	pub,priv := testKeys(t,FIPS_P256)
+	for _,n := range segmentSizes {
+		ct := encryptBytes(t,[]*PublicKey{pub},nil,testMessage(n))
+		hl := headerLength(ct)
+		full := segmentSize+16
+		for _,c := range []int{0,5,hl-1,hl+1,len(ct)-1} {
+			if _,e := decryptBytes(priv,ct[:c]); e==nil { t.Fatal(n,"truncation to",c,"not detected") }
+		}
+		/* Cut at a segment boundary. */
+		for c := hl; c<len(ct); c += full {
+			if _,e := decryptBytes(priv,ct[:c]); e!=ETruncated { t.Fatal(n,"truncation to",c,e) }
+		}
+		/* Data appended after the final segment. */
+		if _,e := decryptBytes(priv,append(append([]byte(nil),ct...),ct[hl:]...)); e==nil { t.Fatal(n,"appended data not detected") }
+	}
+}
+
+func TestEncryptTampered(t *testing.T) {
+	pub,priv := testKeys(t,FIPS_P256)
+	for _,n := range []int{0,1,2*segmentSize+1} {
+		ct := encryptBytes(t,[]*PublicKey{pub},&EncryptOptions{Metadata:[]MetadataEntry{{"name",[]byte("file")}}},testMessage(n))
+		hl := headerLength(ct)
+		for _,pos := range []int{4,8,hl/2,hl-1,hl,hl+(len(ct)-hl)/2,len(ct)-1} {
+			c := append([]byte(nil),ct...)
+			c[pos] ^= 1
+			if _,e := decryptBytes(priv,c); e==nil { t.Fatal(n,"tampering at",pos,"not detected") }
+		}
+	}
+	
+	/* Swapped segments. */
+	ct := encryptBytes(t,[]*PublicKey{pub},nil,testMessage(3*segmentSize))
+	hl := headerLength(ct)
+	full := segmentSize+16
+	c := append([]byte(nil),ct...)
+	copy(c[hl:],ct[hl+full:hl+2*full])
+	copy(c[hl+full:],ct[hl:hl+full])
+	if _,e := decryptBytes(priv,c); e!=EAuthFailed { t.Fatal(e) }
+}
+
+func TestEncryptInvalid(t *testing.T) {
+	pub,priv := testKeys(t,FIPS_P256)
+	if _,e := EncryptWithOptions([]*PublicKey{pub},&EncryptOptions{Suite:LegacyTwofish},rand.Reader,io.Discard); e!=EInvalidSuite { t.Fatal(e) }
+	ct := encryptBytes(t,[]*PublicKey{pub},nil,[]byte("x"))
+	ct[4] = envelopeVersion+1
+	if _,e := decryptBytes(priv,ct); e!=EUnsupportedVersion { t.Fatal(e) }
+	_,other := testKeys(t,FIPS_P384)
+	if _,e := decryptBytes(other,ct); e!=EUnsupportedVersion { t.Fatal(e) }
+	ct[4] = envelopeVersion
+	if _,e := decryptBytes(other,ct); e!=EGroupMismatch { t.Fatal(e) }
+}
+
+func TestDecryptInvalidPeer(t *testing.T) {
+	_,priv := testKeys(t,FFDHE2048)
+	pd,e := newPublicKeyData(&PublicKey{Group:priv.Group,X:big.NewInt(1),Y:new(big.Int),Z:[]byte{}})
+	if e!=nil { t.Fatal(e) }
+	b,_ := asn1.Marshal(recipientsHeader{Nonce:make([]byte,32),Recipients:[]recipientStanza{{*pd,make([]byte,48)}}})
+	c := append(envelopeMagic[:],envelopeVersion)
+	c = binary.BigEndian.AppendUint32(c,uint32(len(b)))
+	c = append(c,b...)
+	if _,e = Decrypt(priv,bytes.NewReader(c)); e!=EIdentityElement { t.Fatal(e) }
+}
@@ -25,7 +25,6 @@ SOFTWARE.
 package generalcryptosystem
 
 import "io"
-import "math/big"
 import "crypto/elliptic"
 
 type ErrorCode uint
@@ -33,20 +32,74 @@ const (
 	EInvalidGroup = ErrorCode(iota)
 	EHeaderTooBig
 	EGroupMismatch
+	EAuthFailed
+	EUnsupportedVersion
+	ETruncated
+	ETrailingData
+	EInvalidOffset
+	ENoRecipient
+	EInvalidSuite
+	EInvalidHeader
+	EInvalidSignature
+	EGroupExists
+	EInvalidKey
+	EKeyOutOfRange
+	ENotOnCurve
+	EIdentityElement
+	ESmallSubgroup
+	EUnsupportedGroup
+	EInvalidEncoding
+	EWrongPassphrase
+	EInvalidKDFParams
+	EInvalidArmor
+	EArmorChecksum
+	EManifestMismatch
+	EObsoleteKey
+	EDigestLength
 )
 func (e ErrorCode) Error() string {
 	switch e {
 	case EInvalidGroup:return "Inavlid group"
 	case EHeaderTooBig:return "Header too big"
 	case EGroupMismatch:return "Group mismatch"
+	case EAuthFailed:return "Message authentication failed"
+	case EUnsupportedVersion:return "Unsupported format version"
+	case ETruncated:return "Ciphertext truncated"
+	case ETrailingData:return "Trailing data after final segment"
+	case EInvalidOffset:return "Invalid offset"
+	case ENoRecipient:return "No matching recipient"
+	case EInvalidSuite:return "Invalid cipher suite"
+	case EInvalidHeader:return "Invalid header"
+	case EInvalidSignature:return "Invalid signature"
+	case EGroupExists:return "Group already exists"
+	case EInvalidKey:return "Invalid public key"
+	case EKeyOutOfRange:return "Public key out of range"
+	case ENotOnCurve:return "Public key not on curve"
+	case EIdentityElement:return "Public key is the identity element"
+	case ESmallSubgroup:return "Public key not in the prime order subgroup"
+	case EUnsupportedGroup:return "Group has no standard key encoding"
+	case EInvalidEncoding:return "Invalid key encoding"
+	case EWrongPassphrase:return "Wrong passphrase"
+	case EInvalidKDFParams:return "Invalid key derivation parameters"
+	case EInvalidArmor:return "Invalid armor"
+	case EArmorChecksum:return "Armor checksum mismatch"
+	case EManifestMismatch:return "File does not match the manifest"
+	case EObsoleteKey:return "Public key of the obsolete Modp17 parameters"
+	case EDigestLength:return "Digest length does not match the hash function"
 	}
 	return "Unknown error"
 }
 
-func generateECKeyPair(curve elliptic.Curve,r io.Reader) (*big.Int,*big.Int,*big.Int,error){
-	priv,x,y,e := elliptic.GenerateKey(curve,r)
-	return new(big.Int).SetBytes(priv),x,y,e
+// The specific errors of public key validation match EInvalidKey, so that
+// errors.Is(e,EInvalidKey) holds for any of them.
+func (e ErrorCode) Is(target error) bool {
+	if target!=EInvalidKey { return false }
+	switch e {
+	case EKeyOutOfRange,ENotOnCurve,EIdentityElement,ESmallSubgroup,EObsoleteKey: return true
+	}
+	return false
 }
+
 func getCurve(group ObjectID) elliptic.Curve{
 	if len(group)<2 { return nil }
 	switch group[0] {
@@ -75,52 +128,48 @@ func getCurve(group ObjectID) elliptic.Curve{
 func GenerateKeyPair(group ObjectID,r io.Reader) (*PublicKey,*PrivateKey,error) {
 	var e error
 	
-	if len(group)<2 { return nil,nil,EInvalidGroup }
+	g := getGroupImpl(group)
+	if g==nil { return nil,nil,EInvalidGroup }
 	pub  := new(PublicKey)
 	priv := new(PrivateKey)
 	pub.Group  = group
 	priv.Group = group
 	
-	if group[0]==group_ModP {
-		priv.Secret,pub.X,e  = modpKey(group[1],r)
+	if sg,ok := g.(seededGroup); ok {
+		priv.Seed = make([]byte,32)
+		_,e = io.ReadFull(r,priv.Seed)
 		if e!=nil { return nil,nil,e }
-		pub.Y = new(big.Int).SetUint64(0)
-		pub.Z = []byte{}
-		return pub,priv,nil
-	}
-	
-	if curve := getCurve(group); curve!=nil {
-		priv.Secret,pub.X,pub.Y,e = generateECKeyPair(curve,r)
+		priv.Secret = sg.secretFromSeed(priv.Seed)
+	}else{
+		priv.Secret,e = g.GenerateSecret(r)
 		if e!=nil { return nil,nil,e }
-		pub.Z = []byte{}
-		return pub,priv,nil
 	}
-	
-	return nil,nil,EInvalidGroup
+	g.SetKeyElement(pub,g.BaseMult(priv.Secret))
+	return pub,priv,nil
 }
 
 // Generates the Public Key from the Private Key.
 // This is useful, if the user lost his Public Key.
 // If the operation is not supported, the method shall return nil.
 func (priv *PrivateKey) PublicKey() *PublicKey {
-	var e error
+	g := getGroupImpl(priv.Group)
+	if g==nil { return nil }
 	pub := new(PublicKey )
 	pub.Group = priv.Group
-	if len(priv.Group)<2 { return nil }
-	if priv.Group[0]==group_ModP {
-		pub.X,e = modpExp(priv.Group[1],priv.Secret)
-		if e!=nil { return nil }
-		pub.Y = new(big.Int).SetUint64(0)
-		pub.Z = []byte{}
-		return pub
-	}
-	
-	if curve := getCurve(priv.Group); curve!=nil {
-		pub.X,pub.Y = curve.ScalarBaseMult(priv.Secret.Bytes())
-		pub.Z = []byte{}
-		return pub
-	}
-	
-	return nil
+	g.SetKeyElement(pub,g.BaseMult(priv.Secret))
+	return pub
 }
 
+// Checks, that the public key is a valid element of its group: within range,
+// on the curve, not the identity (or point at infinity) and in the subgroup of
+// the generator. The errors are EInvalidGroup, EInvalidKey, EKeyOutOfRange,
+// ENotOnCurve, EIdentityElement, ESmallSubgroup or EObsoleteKey.
+//
+// Encrypt, Decrypt, Sign and Verify validate the keys they are given.
+func (pub *PublicKey) Validate() error {
+	g := getGroupImpl(pub.Group)
+	if g==nil { return EInvalidGroup }
+	P,e := g.KeyElement(pub)
+	if e!=nil { return e }
+	return g.Validate(P)
+}
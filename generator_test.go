@@ -0,0 +1,149 @@
+/*
+MIT License
+
+Copyright (c) 2017 Simon Schmidt
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+
+package generalcryptosystem
+
+import "io"
+import "errors"
+import "testing"
+import "math/big"
+import "crypto/rand"
+import "crypto/elliptic"
+import "encoding/hex"
+import "filippo.io/edwards25519"
+import "github.com/gtank/ristretto255"
+
+/* Checks, that peer is rejected with want everywhere a public key is used. */
+func testRejected(t *testing.T, priv *PrivateKey, peer *PublicKey, want error) {
+	if e := peer.Validate(); e!=want || !errors.Is(e,EInvalidKey) { t.Fatal(peer.Group,"validate",e,want) }
+	if _,_,e := sharedSecret(priv,peer); e!=want { t.Fatal(peer.Group,"decrypt",e) }
+	if _,e := Encrypt(peer,rand.Reader,io.Discard); e!=want { t.Fatal(peer.Group,"encrypt",e) }
+	if _,e := Verify(peer,&Signature{Sig:big.NewInt(1),Hash:[]byte{1}}); e!=want { t.Fatal(peer.Group,"verify",e) }
+}
+
+func TestValidate(t *testing.T) {
+	for _,g := range testGroups {
+		pub,priv := testKeys(t,g)
+		if e := pub.Validate(); e!=nil { t.Fatal(g,e) }
+		if e := priv.PublicKey().Validate(); e!=nil { t.Fatal(g,e) }
+	}
+}
+
+func TestValidateModP(t *testing.T) {
+	for _,g := range []Group{Modp5,Modp14,FFDHE2048} {
+		_,priv := testKeys(t,g)
+		lg := linearGroups[g.ID()[1]]
+		pm1 := new(big.Int).Sub(lg.P,big.NewInt(1))
+		
+		/* An element outside of the subgroup: x^q = p-1. */
+		nr := big.NewInt(3)
+		for new(big.Int).Exp(nr,lg.Q,lg.P).Cmp(pm1)!=0 { nr.Add(nr,big.NewInt(1)) }
+		
+		for _,c := range []struct{ x *big.Int; want error }{
+			{big.NewInt(0),EKeyOutOfRange},
+			{big.NewInt(1),EIdentityElement},
+			{pm1,EKeyOutOfRange},
+			{lg.P,EKeyOutOfRange},
+			{nr,ESmallSubgroup},
+		} {
+			testRejected(t,priv,&PublicKey{Group:priv.Group,X:c.x,Y:new(big.Int),Z:[]byte{}},c.want)
+		}
+	}
+}
+
+func TestValidateModp17(t *testing.T) {
+	pub,_ := testKeys(t,Modp14)
+	pub.Group = Modp17.ID()
+	if e := pub.Validate(); e!=EObsoleteKey || !errors.Is(e,EInvalidKey) { t.Fatal(e) }
+}
+
+func TestValidateCurve(t *testing.T) {
+	_,priv := testKeys(t,FIPS_P256)
+	p := elliptic.P256().Params().P
+	for _,c := range []struct{ x,y *big.Int; want error }{
+		{new(big.Int),new(big.Int),EIdentityElement},
+		{big.NewInt(1),big.NewInt(2),ENotOnCurve},
+		{p,big.NewInt(2),EKeyOutOfRange},
+		{big.NewInt(-1),big.NewInt(2),EKeyOutOfRange},
+	} {
+		testRejected(t,priv,&PublicKey{Group:priv.Group,X:c.x,Y:c.y,Z:[]byte{}},c.want)
+	}
+}
+
+func TestValidateComplex(t *testing.T) {
+	_,priv := testKeys(t,Complex_2048bit)
+	m := complexGroups[1].Modulus
+	for _,c := range []struct{ x,y *big.Int; want error }{
+		{new(big.Int),new(big.Int),EIdentityElement},
+		{big.NewInt(1),new(big.Int),EIdentityElement},
+		{m,big.NewInt(1),EKeyOutOfRange},
+		{big.NewInt(-1),big.NewInt(1),EKeyOutOfRange},
+	} {
+		testRejected(t,priv,&PublicKey{Group:priv.Group,X:c.x,Y:c.y,Z:[]byte{}},c.want)
+	}
+	/* -1 has order 2, but the small subgroups cannot be ruled out. */
+	low := &PublicKey{Group:priv.Group,X:new(big.Int).Sub(m,big.NewInt(1)),Y:new(big.Int),Z:[]byte{}}
+	if e := low.Validate(); e!=nil { t.Fatal(e) }
+}
+
+func TestValidateEd25519(t *testing.T) {
+	pub,priv := testKeys(t,Ed25519)
+	key := func(b []byte) *PublicKey { return &PublicKey{Group:priv.Group,X:new(big.Int),Y:new(big.Int),Z:b} }
+	
+	/* (0,-1) has order 2. */
+	low,_ := hex.DecodeString("ecffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f")
+	testRejected(t,priv,key(low),ESmallSubgroup)
+	P,_ := new(edwards25519.Point).SetBytes(pub.Z)
+	T,_ := new(edwards25519.Point).SetBytes(low)
+	testRejected(t,priv,key(new(edwards25519.Point).Add(P,T).Bytes()),ESmallSubgroup)
+	testRejected(t,priv,key(edwards25519.NewIdentityPoint().Bytes()),EIdentityElement)
+	testRejected(t,priv,key(low[1:]),EKeyOutOfRange)
+}
+
+func TestValidateX25519(t *testing.T) {
+	_,priv := testKeys(t,X25519)
+	/* The points of small order, also in non-canonical encodings. */
+	for _,u := range []string{
+		"0000000000000000000000000000000000000000000000000000000000000000",
+		"0100000000000000000000000000000000000000000000000000000000000000",
+		"e0eb7a7c3b41b8ae1656e3faf19fc46ada098deb9c32b1fd866205165f49b800",
+		"5f9c95bca3508c24b1d0b1559c83ef5b04445cc4581c8e86d8224eddd09f1157",
+		"ecffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f",
+		"edffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f",
+		"eeffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f",
+	} {
+		b,_ := hex.DecodeString(u)
+		peer := &PublicKey{Group:priv.Group,X:new(big.Int),Y:new(big.Int),Z:b}
+		if e := peer.Validate(); !errors.Is(e,EInvalidKey) { t.Fatal(u,e) }
+		if _,_,e := sharedSecret(priv,peer); !errors.Is(e,EInvalidKey) { t.Fatal(u,e) }
+	}
+}
+
+func TestValidateRistretto255(t *testing.T) {
+	_,priv := testKeys(t,Ristretto255)
+	testRejected(t,priv,&PublicKey{Group:priv.Group,X:new(big.Int),Y:new(big.Int),Z:ristretto255.NewElement().Encode(nil)},EIdentityElement)
+	/* A non-canonical encoding. */
+	testRejected(t,priv,&PublicKey{Group:priv.Group,X:new(big.Int),Y:new(big.Int),Z:make([]byte,31)},EKeyOutOfRange)
+}
@@ -0,0 +1,133 @@
+/*
+MIT License
+
+Copyright (c) 2017 Simon Schmidt
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package generalcryptosystem
+
+import "io"
+import "math/big"
+
+// An element of a group. The concrete type is up to the GroupImpl, that
+// created it, and elements must only be passed to the same GroupImpl.
+type Element interface{}
+
+/*
+The arithmetic of a group family. Key generation, encryption, decryption and
+signatures are built on top of this interface only.
+*/
+type GroupImpl interface {
+	// Returns the order of the generator, or a multiple of it, if the order
+	// is not known.
+	Order() *big.Int
+	
+	// Draws a random secret exponent (scalar).
+	GenerateSecret(r io.Reader) (*big.Int,error)
+	
+	// Returns k*G, where G is the generator.
+	BaseMult(k *big.Int) Element
+	
+	// Returns k*P.
+	Mult(k *big.Int, p Element) Element
+	
+	// Returns P+Q.
+	Add(p, q Element) Element
+	
+	// Encodes the element with the fixed length of the group.
+	Encode(p Element) []byte
+	
+	// Decodes the output of Encode.
+	Decode(b []byte) (Element,error)
+	
+	// Reads the element from the fields of a public key.
+	KeyElement(pub *PublicKey) (Element,error)
+	
+	// Stores the element in the fields of a public key. All of X, Y and Z
+	// must be set, as the public key is ASN.1 encoded.
+	SetKeyElement(pub *PublicKey, p Element)
+	
+	// Checks, that the element is a valid element of the group, other than
+	// the identity, and lies in the subgroup generated by the generator.
+	// Should return one of EKeyOutOfRange, ENotOnCurve, EIdentityElement or
+	// ESmallSubgroup.
+	Validate(p Element) error
+}
+
+// Implemented by groups, whose private keys are derived from a 32 byte seed.
+type seededGroup interface{
+	secretFromSeed(seed []byte) *big.Int
+}
+
+// Returns the implementation of the group, or nil.
+func getGroupImpl(group ObjectID) GroupImpl {
+	if len(group)<2 { return nil }
+	if group[0]>group_Reserved { return lookupRegistered(group) }
+	switch group[0] {
+	case group_ModP:
+		if g,ok := linearGroups[group[1]]; ok { return g }
+		return nil
+	case group_Curve25519:
+		switch group[1] {
+		case c25519_Ed: return c25519Group{false}
+		case c25519_X:  return c25519Group{true}
+		}
+		return nil
+	case group_Ristretto:
+		if group[1]==1 { return r255Group{} }
+		return nil
+	case group_ComplxGroup:
+		/* The order of every element of GF(p^2) divides p^2-1. */
+		if g,ok := complexGroups[group[1]]; ok {
+			n := new(big.Int).Mul(g.Modulus,g.Modulus)
+			return &curveGroup{g.AsCurve(),n.Sub(n,big.NewInt(1)),false}
+		}
+		return nil
+	}
+	if curve := getCurve(group); curve!=nil { return &curveGroup{curve,curve.Params().N,false} }
+	return nil
+}
+
+// Returns the implementation of the built-in or registered group, or nil.
+func (id ObjectID) Impl() GroupImpl {
+	return getGroupImpl(id)
+}
+
+// Returns the order of the generator of the group, or nil.
+func groupOrder(group ObjectID) *big.Int {
+	g := getGroupImpl(group)
+	if g==nil { return nil }
+	return g.Order()
+}
+
+/*
+Returns the encoding of an element, as used by the legacy format and the
+signature commitment only: The coordinates with the leading zeros stripped. The
+Curve25519 groups use the compressed Edwards point.
+*/
+func legacyBytes(g GroupImpl, p Element) []byte {
+	switch v := p.(type) {
+	case *big.Int: return v.Bytes()
+	case *ecPoint: return append(v.X.Bytes(),v.Y.Bytes()...)
+	}
+	if _,ok := g.(c25519Group); ok { return c25519Group{false}.Encode(p) }
+	return g.Encode(p)
+}
@@ -0,0 +1,122 @@
+/*
+MIT License
+
+Copyright (c) 2017 Simon Schmidt
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package generalcryptosystem
+
+import "io"
+import "encoding/asn1"
+import "encoding/binary"
+
+// A key-value pair stored in the envelope header.
+type MetadataEntry struct{
+	Key   string `asn1:"utf8"`
+	Value []byte
+}
+
+/*
+The public part of a ciphertext header, as returned by ParseHeader.
+
+Version 0 denotes the legacy format, which is Twofish-CBC without
+authentication. Its Suite is LegacyTwofish.
+*/
+type Header struct{
+	Version    int
+	Suite      Suite
+	Flags      int
+	
+	// The group of every recipient stanza, nil for unknown object identifiers.
+	Recipients []ObjectID
+	
+	Metadata   []MetadataEntry
+}
+
+// The parsed prefix and header of an envelope.
+type parsedEnvelope struct{
+	version byte
+	ad      []byte
+	multi   *recipientsHeader
+}
+
+// Reads the envelope prefix and header, after the magic bytes. Versions other
+// than envelopeVersion and unknown flags are rejected.
+func parseEnvelope(src io.Reader) (*parsedEnvelope,error) {
+	var pre [5]byte
+	_,e := io.ReadFull(src,pre[:])
+	if e!=nil { return nil,e }
+	if pre[0]!=envelopeVersion { return nil,EUnsupportedVersion }
+	hl := binary.BigEndian.Uint32(pre[1:])
+	if hl > (1<<20) { return nil,EHeaderTooBig }
+	
+	p := new(parsedEnvelope)
+	p.version = pre[0]
+	p.ad = make([]byte,9+int(hl))
+	copy(p.ad,envelopeMagic[:])
+	copy(p.ad[4:],pre[:])
+	_,e = io.ReadFull(src,p.ad[9:])
+	if e!=nil { return nil,e }
+	
+	p.multi = new(recipientsHeader)
+	_,e = asn1.Unmarshal(p.ad[9:],p.multi)
+	if e!=nil { return nil,e }
+	if p.multi.Flags!=0 { return nil,EInvalidHeader }
+	if p.multi.Suite<0 || !Suite(p.multi.Suite).Valid() { return nil,EInvalidSuite }
+	return p,nil
+}
+
+func (p *parsedEnvelope) header() *Header {
+	h := new(Header)
+	h.Version = int(p.version)
+	h.Suite = Suite(p.multi.Suite)
+	h.Flags = p.multi.Flags
+	h.Metadata = p.multi.Metadata
+	for _,st := range p.multi.Recipients {
+		id,_ := parseGroupID(st.Peer.Group)
+		h.Recipients = append(h.Recipients,id)
+	}
+	return h
+}
+
+// Reads the header of a ciphertext produced by Encrypt, without decrypting
+// it. After it returns, src is positioned at the start of the payload.
+func ParseHeader(src io.Reader) (*Header,error) {
+	var pre [4]byte
+	_,e := io.ReadFull(src,pre[:])
+	if e!=nil { return nil,e }
+	if pre==envelopeMagic {
+		p,e := parseEnvelope(src)
+		if e!=nil { return nil,e }
+		return p.header(),nil
+	}
+	
+	hl := binary.BigEndian.Uint32(pre[:])
+	if hl > (1<<20) { return nil,EHeaderTooBig }
+	b := make([]byte,int(hl)+16)
+	_,e = io.ReadFull(src,b)
+	if e!=nil { return nil,e }
+	peer := new(PublicKey)
+	_,e = asn1.Unmarshal(b[:hl],peer)
+	if e!=nil { return nil,e }
+	return &Header{Suite:LegacyTwofish,Recipients:[]ObjectID{peer.Group}},nil
+}
+
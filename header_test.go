@@ -0,0 +1,84 @@
+/*
+MIT License
+
+Copyright (c) 2017 Simon Schmidt
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+
+package generalcryptosystem
+
+import "io"
+import "bytes"
+import "testing"
+import "encoding/asn1"
+import "encoding/binary"
+
+func sameGroup(a, b ObjectID) bool {
+	if len(a)!=len(b) { return false }
+	for i := range a {
+		if a[i]!=b[i] { return false }
+	}
+	return true
+}
+
+func TestParseHeader(t *testing.T) {
+	pub,_ := testKeys(t,FIPS_P256)
+	pub2,_ := testKeys(t,Modp5)
+	md := []MetadataEntry{{"name",[]byte("x.tar")}}
+	ct := encryptBytes(t,[]*PublicKey{pub,pub2},&EncryptOptions{Suite:AES256GCM,Metadata:md},[]byte("hi"))
+	src := bytes.NewReader(ct)
+	h,e := ParseHeader(src)
+	if e!=nil { t.Fatal(e) }
+	if h.Version!=envelopeVersion || h.Suite!=AES256GCM || h.Flags!=0 { t.Fatal(h) }
+	if len(h.Recipients)!=2 || !sameGroup(h.Recipients[0],pub.Group) || !sameGroup(h.Recipients[1],pub2.Group) { t.Fatal(h.Recipients) }
+	if len(h.Metadata)!=1 || h.Metadata[0].Key!="name" || string(h.Metadata[0].Value)!="x.tar" { t.Fatal(h.Metadata) }
+	if rest,_ := io.ReadAll(src); len(rest)!=len(ct)-headerLength(ct) { t.Fatal("not positioned at the payload") }
+}
+
+func TestParseHeaderLegacy(t *testing.T) {
+	pub,_ := testKeys(t,FIPS_P256)
+	h,e := ParseHeader(bytes.NewReader(legacyEncrypt(t,pub,[]byte("x"))))
+	if e!=nil || h.Version!=0 || len(h.Recipients)!=1 || !sameGroup(h.Recipients[0],pub.Group) { t.Fatal(h,e) }
+	/* The legacy format has no MAC, it must not be reported as TwofishCBC. */
+	if h.Suite!=LegacyTwofish || h.Suite.Valid() || h.Suite.String()!="Twofish-256-CBC (unauthenticated)" { t.Fatal(h.Suite) }
+}
+
+func TestParseHeaderInvalid(t *testing.T) {
+	pub,priv := testKeys(t,FIPS_P256)
+	ct := encryptBytes(t,[]*PublicKey{pub},nil,nil)
+	for _,v := range []byte{0,envelopeVersion+1} {
+		c := append([]byte(nil),ct...)
+		c[4] = v
+		if _,e := ParseHeader(bytes.NewReader(c)); e!=EUnsupportedVersion { t.Fatal(v,e) }
+	}
+	c := append([]byte(nil),ct...)
+	c[5] = 0xff
+	if _,e := ParseHeader(bytes.NewReader(c)); e!=EHeaderTooBig { t.Fatal(e) }
+	if _,e := ParseHeader(bytes.NewReader(ct[:headerLength(ct)-1])); e==nil { t.Fatal("short header accepted") }
+	
+	/* Headers with unknown flags are rejected. */
+	b,_ := asn1.Marshal(recipientsHeader{Nonce:make([]byte,32),Flags:1})
+	c = append(envelopeMagic[:],envelopeVersion)
+	c = binary.BigEndian.AppendUint32(c,uint32(len(b)))
+	c = append(c,b...)
+	if _,e := ParseHeader(bytes.NewReader(c)); e!=EInvalidHeader { t.Fatal(e) }
+	if _,e := decryptBytes(priv,c); e!=EInvalidHeader { t.Fatal(e) }
+}
@@ -0,0 +1,180 @@
+/*
+MIT License
+
+Copyright (c) 2017 Simon Schmidt
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+
+package generalcryptosystem
+
+import "bytes"
+import "math/big"
+import "encoding/json"
+import "encoding/base64"
+
+/*
+JSON Web Keys (RFC 7517) are supported for the groups with a registered JOSE
+curve name: P-256, P-384 and P-521 (RFC 7518), secp256k1 (RFC 8812) as "EC"
+keys, and Ed25519 and X25519 (RFC 8037) as "OKP" keys. RFC 8812 registers no
+names for the Brainpool curves, and there is none for P-224, the other Koblitz
+curves, the ModP and the complex groups or ristretto255. These fail with
+EUnsupportedGroup.
+
+The private key "d" is the secret with the length of the group order for
+"EC" keys, and the seed (see PrivateKey.Seed) for "OKP" keys.
+*/
+
+var jwkCurves = map[Group]string{
+	FIPS_P256: "P-256",
+	FIPS_P384: "P-384",
+	FIPS_P521: "P-521",
+	Koblitz_S256: "secp256k1",
+	Ed25519: "Ed25519",
+	X25519: "X25519",
+}
+
+type jsonWebKey struct{
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y,omitempty"`
+	D   string `json:"d,omitempty"`
+}
+
+var b64 = base64.RawURLEncoding.Strict()
+
+// Decodes a base64url value, that must have exactly n bytes.
+func jwkBytes(s string, n int) ([]byte,error) {
+	b,e := b64.DecodeString(s)
+	if e!=nil || len(b)!=n { return nil,EInvalidEncoding }
+	return b,nil
+}
+
+func jwkGroup(group ObjectID) (string,GroupImpl,error) {
+	g := getGroupImpl(group)
+	if g==nil { return "",nil,EInvalidGroup }
+	gr,ok := groupOf(group)
+	if !ok { return "",nil,EUnsupportedGroup }
+	crv,ok := jwkCurves[gr]
+	if !ok { return "",nil,EUnsupportedGroup }
+	return crv,g,nil
+}
+
+func (pub *PublicKey) jwk() (*jsonWebKey,error) {
+	crv,g,e := jwkGroup(pub.Group)
+	if e!=nil { return nil,e }
+	P,e := g.KeyElement(pub)
+	if e!=nil { return nil,e }
+	b := g.Encode(P)
+	if _,ok := g.(*curveGroup); ok {
+		return &jsonWebKey{Kty:"EC",Crv:crv,X:b64.EncodeToString(b[:len(b)/2]),Y:b64.EncodeToString(b[len(b)/2:])},nil
+	}
+	return &jsonWebKey{Kty:"OKP",Crv:crv,X:b64.EncodeToString(b)},nil
+}
+
+// Encodes the public key as JSON Web Key.
+func (pub *PublicKey) MarshalJWK() ([]byte,error) {
+	k,e := pub.jwk()
+	if e!=nil { return nil,e }
+	return json.Marshal(k)
+}
+
+// Encodes the private key as JSON Web Key, including the public key.
+func (priv *PrivateKey) MarshalJWK() ([]byte,error) {
+	pub := priv.PublicKey()
+	if pub==nil { return nil,EInvalidGroup }
+	k,e := pub.jwk()
+	if e!=nil { return nil,e }
+	if k.Kty=="EC" {
+		n := groupOrder(priv.Group)
+		if priv.Secret==nil || priv.Secret.Sign()<=0 || priv.Secret.Cmp(n)>=0 { return nil,EInvalidKey }
+		k.D = b64.EncodeToString(priv.Secret.FillBytes(make([]byte,(n.BitLen()+7)/8)))
+	}else{
+		sg := getGroupImpl(priv.Group).(seededGroup)
+		if len(priv.Seed)!=32 { return nil,EUnsupportedGroup }
+		if sg.secretFromSeed(priv.Seed).Cmp(priv.Secret)!=0 { return nil,EInvalidKey }
+		k.D = b64.EncodeToString(priv.Seed)
+	}
+	return json.Marshal(k)
+}
+
+// Parses the public part of a JSON Web Key. The key is validated.
+func ParseJWK(b []byte) (*PublicKey,error) {
+	var k jsonWebKey
+	if e := json.Unmarshal(b,&k); e!=nil { return nil,EInvalidEncoding }
+	group,g,P,e := k.element()
+	if e!=nil { return nil,e }
+	pub := &PublicKey{Group:group}
+	g.SetKeyElement(pub,P)
+	return pub,nil
+}
+
+// Decodes and validates the public key.
+func (k *jsonWebKey) element() (ObjectID,GroupImpl,Element,error) {
+	var group ObjectID
+	for gr,crv := range jwkCurves {
+		if crv==k.Crv { group = gr.ID() }
+	}
+	if group==nil { return nil,nil,nil,EUnsupportedGroup }
+	g := getGroupImpl(group)
+	cg,isEC := g.(*curveGroup)
+	if (k.Kty=="EC")!=isEC || (k.Kty!="EC" && k.Kty!="OKP") { return nil,nil,nil,EInvalidEncoding }
+	
+	var b []byte
+	if isEC {
+		x,e := jwkBytes(k.X,cg.size())
+		if e!=nil { return nil,nil,nil,e }
+		y,e := jwkBytes(k.Y,cg.size())
+		if e!=nil { return nil,nil,nil,e }
+		b = append(x,y...)
+	}else{
+		if k.Y!="" { return nil,nil,nil,EInvalidEncoding }
+		x,e := jwkBytes(k.X,32)
+		if e!=nil { return nil,nil,nil,e }
+		b = x
+	}
+	P,e := g.Decode(b)
+	if e!=nil { return nil,nil,nil,EInvalidEncoding }
+	if e = g.Validate(P); e!=nil { return nil,nil,nil,e }
+	return group,g,P,nil
+}
+
+// Parses a private JSON Web Key. The public key in it must match the secret.
+func ParsePrivateJWK(b []byte) (*PrivateKey,error) {
+	var k jsonWebKey
+	if e := json.Unmarshal(b,&k); e!=nil { return nil,EInvalidEncoding }
+	group,g,P,e := k.element()
+	if e!=nil { return nil,e }
+	priv := &PrivateKey{Group:group}
+	if sg,ok := g.(seededGroup); ok {
+		priv.Seed,e = jwkBytes(k.D,32)
+		if e!=nil { return nil,e }
+		priv.Secret = sg.secretFromSeed(priv.Seed)
+	}else{
+		n := g.Order()
+		d,e := jwkBytes(k.D,(n.BitLen()+7)/8)
+		if e!=nil { return nil,e }
+		priv.Secret = new(big.Int).SetBytes(d)
+		if priv.Secret.Sign()<=0 || priv.Secret.Cmp(n)>=0 { return nil,EInvalidKey }
+	}
+	if !bytes.Equal(g.Encode(P),g.Encode(g.BaseMult(priv.Secret))) { return nil,EInvalidKey }
+	return priv,nil
+}
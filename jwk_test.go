@@ -0,0 +1,79 @@
+/*
+MIT License
+
+Copyright (c) 2017 Simon Schmidt
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+
+package generalcryptosystem
+
+import "testing"
+import "encoding/json"
+import "crypto/ed25519"
+import "crypto/elliptic"
+import "encoding/base64"
+
+func TestJWK(t *testing.T) {
+	for _,g := range []Group{FIPS_P256,FIPS_P521,Koblitz_S256,Ed25519,X25519} {
+		pub,priv := testKeys(t,g)
+		b,e := pub.MarshalJWK()
+		if e!=nil { t.Fatal(g,e) }
+		p2,e := ParseJWK(b)
+		if e!=nil || !p2.Equal(pub) { t.Fatal(g,e) }
+		sb,e := priv.MarshalJWK()
+		if e!=nil { t.Fatal(g,e) }
+		s2,e := ParsePrivateJWK(sb)
+		if e!=nil || s2.Secret.Cmp(priv.Secret)!=0 { t.Fatal(g,e) }
+		if p2,e = ParseJWK(sb); e!=nil || !p2.Equal(pub) { t.Fatal(g,e) }
+		
+		/* The private key does not match the public key. */
+		_,other := testKeys(t,g)
+		ob,_ := other.MarshalJWK()
+		var m1,m2 map[string]string
+		json.Unmarshal(sb,&m1)
+		json.Unmarshal(ob,&m2)
+		m1["d"] = m2["d"]
+		mb,_ := json.Marshal(m1)
+		if _,e = ParsePrivateJWK(mb); e!=EInvalidKey { t.Fatal(g,e) }
+	}
+	for _,g := range []Group{Modp14,Complex_2048bit,Brainpool_P256r1,Ristretto255,FIPS_P224} {
+		pub,_ := testKeys(t,g)
+		if _,e := pub.MarshalJWK(); e!=EUnsupportedGroup { t.Fatal(g,e) }
+	}
+	if _,e := ParseJWK([]byte(`{"kty":"EC","crv":"BP-256","x":"","y":""}`)); e!=EUnsupportedGroup { t.Fatal(e) }
+	if _,e := ParseJWK([]byte(`{"kty":"OKP","crv":"P-256","x":"","y":""}`)); e!=EInvalidEncoding { t.Fatal(e) }
+}
+
+/* "d" is the scalar of P-256 and the seed of Ed25519. */
+func TestJWKStdlib(t *testing.T) {
+	var m map[string]string
+	pub,priv := testKeys(t,FIPS_P256)
+	b,_ := priv.MarshalJWK()
+	json.Unmarshal(b,&m)
+	d,_ := base64.RawURLEncoding.DecodeString(m["d"])
+	x,y := elliptic.P256().ScalarBaseMult(d)
+	if x.Cmp(pub.X)!=0 || y.Cmp(pub.Y)!=0 { t.Fatal("P-256") }
+	pub,priv = testKeys(t,Ed25519)
+	b,_ = priv.MarshalJWK()
+	json.Unmarshal(b,&m)
+	d,_ = base64.RawURLEncoding.DecodeString(m["d"])
+	if string(ed25519.NewKeyFromSeed(d).Public().(ed25519.PublicKey))!=string(pub.Z) { t.Fatal("Ed25519") }
+}
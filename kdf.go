@@ -0,0 +1,65 @@
+/*
+MIT License
+
+Copyright (c) 2017 Simon Schmidt
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package generalcryptosystem
+
+import "io"
+import "hash"
+import "encoding/binary"
+import "golang.org/x/crypto/blake2b"
+import "golang.org/x/crypto/hkdf"
+
+/*
+Key derivation for the envelope is done by HKDF with BLAKE2b-512. The info
+string is the concatenation of the label and the context values, each of them
+prefixed by its length (uint32, big endian), so that no two different contexts
+give the same info string.
+
+For the key encryption key of a recipient, the secret is the shared element
+and the context is the group identifier (ASN.1), the ephemeral public key and
+the public key of the recipient, all elements with the fixed length of the
+group (see GroupImpl.Encode). The salt is the random nonce of the header.
+*/
+
+const (
+	kdfLabelRecipient = "generalcryptosystem recipient"
+	kdfLabelPayload   = "generalcryptosystem payload"
+)
+
+func newBlake2b512() hash.Hash {
+	h,_ := blake2b.New512(nil)
+	return h
+}
+
+// Returns the output of HKDF-BLAKE2b-512 as io.Reader.
+func kdf(secret, salt []byte, label string, context ...[]byte) io.Reader {
+	info := binary.BigEndian.AppendUint32(nil,uint32(len(label)))
+	info = append(info,label...)
+	for _,c := range context {
+		info = binary.BigEndian.AppendUint32(info,uint32(len(c)))
+		info = append(info,c...)
+	}
+	return hkdf.New(newBlake2b512,secret,salt,info)
+}
+
@@ -63,10 +63,32 @@ const (
 	Brainpool_P512r1
 	Brainpool_P512t1
 	
-	/* Complex number groups, see github.com/maxymania/complexdh */
+	/*
+	Complex number groups, see github.com/maxymania/complexdh: The
+	multiplicative group of GF(p^2) over the primes of Modp14, Modp16 and
+	Modp18. Its order p^2-1 has small factors, so Validate cannot rule out
+	elements of small subgroups.
+	
+	Deprecated: Kept for existing keys and ciphertexts only. Use the ModP or
+	FFDHE groups, or a curve, for new keys.
+	*/
 	Complex_2048bit
 	Complex_4096bit
 	Complex_8192bit
+	
+	/* Curve25519 (see curve25519.go) */
+	Ed25519 /* edwards25519, RFC 8032 encoding */
+	X25519 /* Curve25519, RFC 7748 encoding */
+	
+	/* Prime order groups */
+	Ristretto255 /* ristretto255 (see RFC 9496) */
+	
+	/* ModP groups (see RFC-7919), added after the others to keep the values. */
+	FFDHE2048 /* ffdhe2048 */
+	FFDHE3072 /* ffdhe3072 */
+	FFDHE4096 /* ffdhe4096 */
+	FFDHE6144 /* ffdhe6144 */
+	FFDHE8192 /* ffdhe8192 */
 )
 
 const (
@@ -75,6 +97,8 @@ const (
 	group_EcKoblitz = 3
 	group_EcBrainpool = 4
 	group_ComplxGroup = 5
+	group_Curve25519 = 6
+	group_Ristretto = 7
 )
 
 type ObjectID []int
@@ -90,6 +114,14 @@ func (g Group) ID() ObjectID {
 	return nil
 }
 
+var groupNames = make(map[Group]string)
+
+// Returns the name of the group, as accepted by GroupByName.
+func (g Group) String() string {
+	if n,ok := groupNames[g]; ok { return n }
+	return "unknown"
+}
+
 func init(){
 	groups[Modp5]  = ObjectID{group_ModP, 5}
 	groups[Modp14] = ObjectID{group_ModP,14}
@@ -98,6 +130,12 @@ func init(){
 	groups[Modp17] = ObjectID{group_ModP,17}
 	groups[Modp18] = ObjectID{group_ModP,18}
 	
+	groups[FFDHE2048] = ObjectID{group_ModP,256}
+	groups[FFDHE3072] = ObjectID{group_ModP,257}
+	groups[FFDHE4096] = ObjectID{group_ModP,258}
+	groups[FFDHE6144] = ObjectID{group_ModP,259}
+	groups[FFDHE8192] = ObjectID{group_ModP,260}
+	
 	groups[FIPS_P224] = ObjectID{group_EcFips,224}
 	groups[FIPS_P256] = ObjectID{group_EcFips,256}
 	groups[FIPS_P384] = ObjectID{group_EcFips,384}
@@ -126,21 +164,84 @@ func init(){
 	groups[Complex_2048bit] = ObjectID{group_ComplxGroup,1}
 	groups[Complex_4096bit] = ObjectID{group_ComplxGroup,2}
 	groups[Complex_8192bit] = ObjectID{group_ComplxGroup,3}
+	
+	groups[Ed25519] = ObjectID{group_Curve25519,c25519_Ed}
+	groups[X25519]  = ObjectID{group_Curve25519,c25519_X}
+	
+	groups[Ristretto255] = ObjectID{group_Ristretto,1}
+	
+	groupNames[Modp5]  = "modp5"
+	groupNames[Modp14] = "modp14"
+	groupNames[Modp15] = "modp15"
+	groupNames[Modp16] = "modp16"
+	groupNames[Modp17] = "modp17"
+	groupNames[Modp18] = "modp18"
+	
+	groupNames[FFDHE2048] = "ffdhe2048"
+	groupNames[FFDHE3072] = "ffdhe3072"
+	groupNames[FFDHE4096] = "ffdhe4096"
+	groupNames[FFDHE6144] = "ffdhe6144"
+	groupNames[FFDHE8192] = "ffdhe8192"
+	
+	groupNames[FIPS_P224] = "P-224"
+	groupNames[FIPS_P256] = "P-256"
+	groupNames[FIPS_P384] = "P-384"
+	groupNames[FIPS_P521] = "P-521"
+	
+	groupNames[Koblitz_S160] = "secp160k1"
+	groupNames[Koblitz_S192] = "secp192k1"
+	groupNames[Koblitz_S224] = "secp224k1"
+	groupNames[Koblitz_S256] = "secp256k1"
+	
+	groupNames[Brainpool_P160r1] = "brainpoolP160r1"
+	groupNames[Brainpool_P160t1] = "brainpoolP160t1"
+	groupNames[Brainpool_P192r1] = "brainpoolP192r1"
+	groupNames[Brainpool_P192t1] = "brainpoolP192t1"
+	groupNames[Brainpool_P224r1] = "brainpoolP224r1"
+	groupNames[Brainpool_P224t1] = "brainpoolP224t1"
+	groupNames[Brainpool_P256r1] = "brainpoolP256r1"
+	groupNames[Brainpool_P256t1] = "brainpoolP256t1"
+	groupNames[Brainpool_P320r1] = "brainpoolP320r1"
+	groupNames[Brainpool_P320t1] = "brainpoolP320t1"
+	groupNames[Brainpool_P384r1] = "brainpoolP384r1"
+	groupNames[Brainpool_P384t1] = "brainpoolP384t1"
+	groupNames[Brainpool_P512r1] = "brainpoolP512r1"
+	groupNames[Brainpool_P512t1] = "brainpoolP512t1"
+	
+	groupNames[Complex_2048bit] = "complex2048"
+	groupNames[Complex_4096bit] = "complex4096"
+	groupNames[Complex_8192bit] = "complex8192"
+	
+	groupNames[Ed25519] = "Ed25519"
+	groupNames[X25519]  = "X25519"
+	
+	groupNames[Ristretto255] = "ristretto255"
 }
 
 type PublicKey struct{
 	Group ObjectID
 	X,Y *big.Int
-	Z []byte
+	Z []byte /* The encoded element for groups without coordinates, such as X25519 or ristretto255. */
 }
 
 type PrivateKey struct{
 	Group ObjectID
 	Secret *big.Int
+	
+	// The seed, from which Secret was derived, for Ed25519 and X25519 keys
+	// (RFC 8032, RFC 7748). It is needed to export the key in PKCS #8 form.
+	Seed []byte `asn1:"optional"`
 }
 
 type Signature struct{
 	Sig *big.Int
 	Hash []byte
+	
+	// The signature scheme, SigLegacy or SigCompact.
+	Version int `asn1:"optional,default:0"`
+	
+	// The group of the signer, set by Sign. Optional, but required by
+	// MarshalBinary.
+	Group ObjectID `asn1:"optional,explicit,tag:0"`
 }
 
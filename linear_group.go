@@ -33,17 +33,24 @@ import "io"
 type linearGroup struct{
 	P *big.Int
 	G *big.Int
-	Ez int
+	Q *big.Int /* Order of G, (P-1)/2 as P is a safe prime */
+	Ez int /* Secrets are chosen from [0,2^Ez), or from [1,Q) if zero */
 }
 
-var linearGroups = make(map[int]linearGroup)
+var linearGroups = make(map[int]*linearGroup)
 
-func mk_linearGroup(P string,G uint64, Ez int) linearGroup{
+func mk_linearGroup(P string,G uint64, Ez int) *linearGroup{
 	PP,_ := new(big.Int).SetString(P,16)
 	GG := new(big.Int).SetUint64(G)
-	return linearGroup{PP,GG,Ez}
+	QQ := new(big.Int).Rsh(PP,1)
+	return &linearGroup{PP,GG,QQ,Ez}
 }
 
+/*
+RFC 3526 groups, with their group numbers. Ez is the upper exponent size given
+in section 8 of RFC 3526. The secrets of the RFC 7919 groups are taken modulo
+the order q. SelfTest checks them against the standards.
+*/
 func init() {
 	linearGroups[5] = mk_linearGroup(
 "FFFFFFFFFFFFFFFFC90FDAA22168C234C4C6628B80DC1CD1"+
@@ -119,7 +126,28 @@ func init() {
 "670C354E4ABC9804F1746C08CA18217C32905E462E36CE3B"+
 "E39E772C180E86039B2783A2EC07A28FB5C55DF06F4C52C9"+
 "DE2BCBF6955817183995497CEA956AE515D2261898FA0510"+
-"15728E5A8AACAA68FFFFFFFFFFFFFFFF",2,540)
+"15728E5A8AAAC42DAD33170D04507A33A85521ABDF1CBA64"+
+"ECFB850458DBEF0A8AEA71575D060C7DB3970F85A6E1E4C7"+
+"ABF5AE8CDB0933D71E8C94E04A25619DCEE3D2261AD2EE6B"+
+"F12FFA06D98A0864D87602733EC86A64521F2B18177B200C"+
+"BBE117577A615D6C770988C0BAD946E208E24FA074E5AB31"+
+"43DB5BFCE0FD108E4B82D120A92108011A723C12A787E6D7"+
+"88719A10BDBA5B2699C327186AF4E23C1A946834B6150BDA"+
+"2583E9CA2AD44CE8DBBBC2DB04DE8EF92E8EFC141FBECAA6"+
+"287C59474E6BC05D99B2964FA090C3A2233BA186515BE7ED"+
+"1F612970CEE2D7AFB81BDD762170481CD0069127D5B05AA9"+
+"93B4EA988D8FDDC186FFB7DC90A6C08F4DF435C934028492"+
+"36C3FAB4D27C7026C1D4DCB2602646DEC9751E763DBA37BD"+
+"F8FF9406AD9E530EE5DB382F413001AEB06A53ED9027D831"+
+"179727B0865A8918DA3EDBEBCF9B14ED44CE6CBACED4BB1B"+
+"DB7F1447E6CC254B332051512BD7AF426FB8F401378CD2BF"+
+"5983CA01C64B92ECF032EA15D1721D03F482D7CE6E74FEF6"+
+"D55E702F46980C82B5A84031900B1C9E59E7C97FBEC7E8F3"+
+"23A97A7E36CC88BE0F1D45B7FF585AC54BD407B22B4154AA"+
+"CC8F6D7EBF48E1D814CC5ED20F8037E0A79715EEF29BE328"+
+"06A1D58BB7C5DA76F550AA3D8A1FBFF0EB19CCB1A313D55C"+
+"DA56C9EC2EF29632387FE8D76E3C0468043E8F663F4860EE"+
+"12BF2D5B0B7474D6E694F91E6DCC4024FFFFFFFFFFFFFFFF",2,540)
 	linearGroups[18] = mk_linearGroup(
 "FFFFFFFFFFFFFFFFC90FDAA22168C234C4C6628B80DC1CD1"+
 "29024E088A67CC74020BBEA63B139B22514A08798E3404DD"+
@@ -164,21 +192,192 @@ func init() {
 "4009438B481C6CD7889A002ED5EE382BC9190DA6FC026E47"+
 "9558E4475677E9AA9E3050E2765694DFC81F56E880B96E71"+
 "60C980DD98EDD3DFFFFFFFFFFFFFFFFF",2,620)
+	
+	/* RFC 7919 groups, with the numbers of the TLS supported groups registry */
+	linearGroups[256] = mk_linearGroup( /* ffdhe2048 */
+"FFFFFFFFFFFFFFFFADF85458A2BB4A9AAFDC5620273D3CF1"+
+"D8B9C583CE2D3695A9E13641146433FBCC939DCE249B3EF9"+
+"7D2FE363630C75D8F681B202AEC4617AD3DF1ED5D5FD6561"+
+"2433F51F5F066ED0856365553DED1AF3B557135E7F57C935"+
+"984F0C70E0E68B77E2A689DAF3EFE8721DF158A136ADE735"+
+"30ACCA4F483A797ABC0AB182B324FB61D108A94BB2C8E3FB"+
+"B96ADAB760D7F4681D4F42A3DE394DF4AE56EDE76372BB19"+
+"0B07A7C8EE0A6D709E02FCE1CDF7E2ECC03404CD28342F61"+
+"9172FE9CE98583FF8E4F1232EEF28183C3FE3B1B4C6FAD73"+
+"3BB5FCBC2EC22005C58EF1837D1683B2C6F34A26C1B2EFFA"+
+"886B423861285C97FFFFFFFFFFFFFFFF",2,0)
+	linearGroups[257] = mk_linearGroup( /* ffdhe3072 */
+"FFFFFFFFFFFFFFFFADF85458A2BB4A9AAFDC5620273D3CF1"+
+"D8B9C583CE2D3695A9E13641146433FBCC939DCE249B3EF9"+
+"7D2FE363630C75D8F681B202AEC4617AD3DF1ED5D5FD6561"+
+"2433F51F5F066ED0856365553DED1AF3B557135E7F57C935"+
+"984F0C70E0E68B77E2A689DAF3EFE8721DF158A136ADE735"+
+"30ACCA4F483A797ABC0AB182B324FB61D108A94BB2C8E3FB"+
+"B96ADAB760D7F4681D4F42A3DE394DF4AE56EDE76372BB19"+
+"0B07A7C8EE0A6D709E02FCE1CDF7E2ECC03404CD28342F61"+
+"9172FE9CE98583FF8E4F1232EEF28183C3FE3B1B4C6FAD73"+
+"3BB5FCBC2EC22005C58EF1837D1683B2C6F34A26C1B2EFFA"+
+"886B4238611FCFDCDE355B3B6519035BBC34F4DEF99C0238"+
+"61B46FC9D6E6C9077AD91D2691F7F7EE598CB0FAC186D91C"+
+"AEFE130985139270B4130C93BC437944F4FD4452E2D74DD3"+
+"64F2E21E71F54BFF5CAE82AB9C9DF69EE86D2BC522363A0D"+
+"ABC521979B0DEADA1DBF9A42D5C4484E0ABCD06BFA53DDEF"+
+"3C1B20EE3FD59D7C25E41D2B66C62E37FFFFFFFFFFFFFFFF",2,0)
+	linearGroups[258] = mk_linearGroup( /* ffdhe4096 */
+"FFFFFFFFFFFFFFFFADF85458A2BB4A9AAFDC5620273D3CF1"+
+"D8B9C583CE2D3695A9E13641146433FBCC939DCE249B3EF9"+
+"7D2FE363630C75D8F681B202AEC4617AD3DF1ED5D5FD6561"+
+"2433F51F5F066ED0856365553DED1AF3B557135E7F57C935"+
+"984F0C70E0E68B77E2A689DAF3EFE8721DF158A136ADE735"+
+"30ACCA4F483A797ABC0AB182B324FB61D108A94BB2C8E3FB"+
+"B96ADAB760D7F4681D4F42A3DE394DF4AE56EDE76372BB19"+
+"0B07A7C8EE0A6D709E02FCE1CDF7E2ECC03404CD28342F61"+
+"9172FE9CE98583FF8E4F1232EEF28183C3FE3B1B4C6FAD73"+
+"3BB5FCBC2EC22005C58EF1837D1683B2C6F34A26C1B2EFFA"+
+"886B4238611FCFDCDE355B3B6519035BBC34F4DEF99C0238"+
+"61B46FC9D6E6C9077AD91D2691F7F7EE598CB0FAC186D91C"+
+"AEFE130985139270B4130C93BC437944F4FD4452E2D74DD3"+
+"64F2E21E71F54BFF5CAE82AB9C9DF69EE86D2BC522363A0D"+
+"ABC521979B0DEADA1DBF9A42D5C4484E0ABCD06BFA53DDEF"+
+"3C1B20EE3FD59D7C25E41D2B669E1EF16E6F52C3164DF4FB"+
+"7930E9E4E58857B6AC7D5F42D69F6D187763CF1D55034004"+
+"87F55BA57E31CC7A7135C886EFB4318AED6A1E012D9E6832"+
+"A907600A918130C46DC778F971AD0038092999A333CB8B7A"+
+"1A1DB93D7140003C2A4ECEA9F98D0ACC0A8291CDCEC97DCF"+
+"8EC9B55A7F88A46B4DB5A851F44182E1C68A007E5E655F6A"+
+"FFFFFFFFFFFFFFFF",2,0)
+	linearGroups[259] = mk_linearGroup( /* ffdhe6144 */
+"FFFFFFFFFFFFFFFFADF85458A2BB4A9AAFDC5620273D3CF1"+
+"D8B9C583CE2D3695A9E13641146433FBCC939DCE249B3EF9"+
+"7D2FE363630C75D8F681B202AEC4617AD3DF1ED5D5FD6561"+
+"2433F51F5F066ED0856365553DED1AF3B557135E7F57C935"+
+"984F0C70E0E68B77E2A689DAF3EFE8721DF158A136ADE735"+
+"30ACCA4F483A797ABC0AB182B324FB61D108A94BB2C8E3FB"+
+"B96ADAB760D7F4681D4F42A3DE394DF4AE56EDE76372BB19"+
+"0B07A7C8EE0A6D709E02FCE1CDF7E2ECC03404CD28342F61"+
+"9172FE9CE98583FF8E4F1232EEF28183C3FE3B1B4C6FAD73"+
+"3BB5FCBC2EC22005C58EF1837D1683B2C6F34A26C1B2EFFA"+
+"886B4238611FCFDCDE355B3B6519035BBC34F4DEF99C0238"+
+"61B46FC9D6E6C9077AD91D2691F7F7EE598CB0FAC186D91C"+
+"AEFE130985139270B4130C93BC437944F4FD4452E2D74DD3"+
+"64F2E21E71F54BFF5CAE82AB9C9DF69EE86D2BC522363A0D"+
+"ABC521979B0DEADA1DBF9A42D5C4484E0ABCD06BFA53DDEF"+
+"3C1B20EE3FD59D7C25E41D2B669E1EF16E6F52C3164DF4FB"+
+"7930E9E4E58857B6AC7D5F42D69F6D187763CF1D55034004"+
+"87F55BA57E31CC7A7135C886EFB4318AED6A1E012D9E6832"+
+"A907600A918130C46DC778F971AD0038092999A333CB8B7A"+
+"1A1DB93D7140003C2A4ECEA9F98D0ACC0A8291CDCEC97DCF"+
+"8EC9B55A7F88A46B4DB5A851F44182E1C68A007E5E0DD902"+
+"0BFD64B645036C7A4E677D2C38532A3A23BA4442CAF53EA6"+
+"3BB454329B7624C8917BDD64B1C0FD4CB38E8C334C701C3A"+
+"CDAD0657FCCFEC719B1F5C3E4E46041F388147FB4CFDB477"+
+"A52471F7A9A96910B855322EDB6340D8A00EF092350511E3"+
+"0ABEC1FFF9E3A26E7FB29F8C183023C3587E38DA0077D9B4"+
+"763E4E4B94B2BBC194C6651E77CAF992EEAAC0232A281BF6"+
+"B3A739C1226116820AE8DB5847A67CBEF9C9091B462D538C"+
+"D72B03746AE77F5E62292C311562A846505DC82DB854338A"+
+"E49F5235C95B91178CCF2DD5CACEF403EC9D1810C6272B04"+
+"5B3B71F9DC6B80D63FDD4A8E9ADB1E6962A69526D43161C1"+
+"A41D570D7938DAD4A40E329CD0E40E65FFFFFFFFFFFFFFFF",2,0)
+	linearGroups[260] = mk_linearGroup( /* ffdhe8192 */
+"FFFFFFFFFFFFFFFFADF85458A2BB4A9AAFDC5620273D3CF1"+
+"D8B9C583CE2D3695A9E13641146433FBCC939DCE249B3EF9"+
+"7D2FE363630C75D8F681B202AEC4617AD3DF1ED5D5FD6561"+
+"2433F51F5F066ED0856365553DED1AF3B557135E7F57C935"+
+"984F0C70E0E68B77E2A689DAF3EFE8721DF158A136ADE735"+
+"30ACCA4F483A797ABC0AB182B324FB61D108A94BB2C8E3FB"+
+"B96ADAB760D7F4681D4F42A3DE394DF4AE56EDE76372BB19"+
+"0B07A7C8EE0A6D709E02FCE1CDF7E2ECC03404CD28342F61"+
+"9172FE9CE98583FF8E4F1232EEF28183C3FE3B1B4C6FAD73"+
+"3BB5FCBC2EC22005C58EF1837D1683B2C6F34A26C1B2EFFA"+
+"886B4238611FCFDCDE355B3B6519035BBC34F4DEF99C0238"+
+"61B46FC9D6E6C9077AD91D2691F7F7EE598CB0FAC186D91C"+
+"AEFE130985139270B4130C93BC437944F4FD4452E2D74DD3"+
+"64F2E21E71F54BFF5CAE82AB9C9DF69EE86D2BC522363A0D"+
+"ABC521979B0DEADA1DBF9A42D5C4484E0ABCD06BFA53DDEF"+
+"3C1B20EE3FD59D7C25E41D2B669E1EF16E6F52C3164DF4FB"+
+"7930E9E4E58857B6AC7D5F42D69F6D187763CF1D55034004"+
+"87F55BA57E31CC7A7135C886EFB4318AED6A1E012D9E6832"+
+"A907600A918130C46DC778F971AD0038092999A333CB8B7A"+
+"1A1DB93D7140003C2A4ECEA9F98D0ACC0A8291CDCEC97DCF"+
+"8EC9B55A7F88A46B4DB5A851F44182E1C68A007E5E0DD902"+
+"0BFD64B645036C7A4E677D2C38532A3A23BA4442CAF53EA6"+
+"3BB454329B7624C8917BDD64B1C0FD4CB38E8C334C701C3A"+
+"CDAD0657FCCFEC719B1F5C3E4E46041F388147FB4CFDB477"+
+"A52471F7A9A96910B855322EDB6340D8A00EF092350511E3"+
+"0ABEC1FFF9E3A26E7FB29F8C183023C3587E38DA0077D9B4"+
+"763E4E4B94B2BBC194C6651E77CAF992EEAAC0232A281BF6"+
+"B3A739C1226116820AE8DB5847A67CBEF9C9091B462D538C"+
+"D72B03746AE77F5E62292C311562A846505DC82DB854338A"+
+"E49F5235C95B91178CCF2DD5CACEF403EC9D1810C6272B04"+
+"5B3B71F9DC6B80D63FDD4A8E9ADB1E6962A69526D43161C1"+
+"A41D570D7938DAD4A40E329CCFF46AAA36AD004CF600C838"+
+"1E425A31D951AE64FDB23FCEC9509D43687FEB69EDD1CC5E"+
+"0B8CC3BDF64B10EF86B63142A3AB8829555B2F747C932665"+
+"CB2C0F1CC01BD70229388839D2AF05E454504AC78B758282"+
+"2846C0BA35C35F5C59160CC046FD8251541FC68C9C86B022"+
+"BB7099876A460E7451A8A93109703FEE1C217E6C3826E52C"+
+"51AA691E0E423CFC99E9E31650C1217B624816CDAD9A95F9"+
+"D5B8019488D9C0A0A1FE3075A577E23183F81D4A3F2FA457"+
+"1EFC8CE0BA8A4FE8B6855DFE72B0A66EDED2FBABFBE58A30"+
+"FAFABE1C5D71A87E2F741EF8C1FE86FEA6BBFDE530677F0D"+
+"97D11D49F7A8443D0822E506A9F4614E011E2A94838FF88C"+
+"D68C8BB7C5C6424CFFFFFFFFFFFFFFFF",2,0)
 }
 
 
-func modpKey(id int,r io.Reader) (*big.Int,*big.Int,error){
-	g,ok := linearGroups[id]
-	if !ok { return nil,nil,EInvalidGroup }
-	priv,e := rand.Int(r,new(big.Int).Lsh(new(big.Int).SetUint64(1),uint(g.Ez)))
-	if e!=nil { return nil,nil,e }
-	pub := new(big.Int).Exp(g.G,priv,g.P)
-	return priv,pub,nil
+/* GroupImpl. The elements are of type *big.Int. */
+
+func (g *linearGroup) Order() *big.Int { return g.Q }
+func (g *linearGroup) GenerateSecret(r io.Reader) (*big.Int,error) {
+	if g.Ez==0 {
+		k,e := rand.Int(r,new(big.Int).Sub(g.Q,big.NewInt(1)))
+		if e!=nil { return nil,e }
+		return k.Add(k,big.NewInt(1)),nil
+	}
+	return rand.Int(r,new(big.Int).Lsh(new(big.Int).SetUint64(1),uint(g.Ez)))
+}
+func (g *linearGroup) BaseMult(k *big.Int) Element {
+	return new(big.Int).Exp(g.G,k,g.P)
+}
+func (g *linearGroup) Mult(k *big.Int, p Element) Element {
+	return new(big.Int).Exp(p.(*big.Int),k,g.P)
+}
+func (g *linearGroup) Add(p, q Element) Element {
+	r := new(big.Int).Mul(p.(*big.Int),q.(*big.Int))
+	return r.Mod(r,g.P)
+}
+func (g *linearGroup) Encode(p Element) []byte {
+	return p.(*big.Int).FillBytes(make([]byte,(g.P.BitLen()+7)/8))
+}
+func (g *linearGroup) Decode(b []byte) (Element,error) {
+	if len(b)!=(g.P.BitLen()+7)/8 { return nil,EInvalidGroup }
+	return new(big.Int).SetBytes(b),nil
+}
+func (g *linearGroup) KeyElement(pub *PublicKey) (Element,error) {
+	if pub.X==nil { return nil,EInvalidKey }
+	return pub.X,nil
+}
+func (g *linearGroup) SetKeyElement(pub *PublicKey, p Element) {
+	pub.X = p.(*big.Int)
+	pub.Y = new(big.Int).SetUint64(0)
+	pub.Z = []byte{}
 }
-func modpExp(id int,ex *big.Int) (*big.Int,error) {
-	g,ok := linearGroups[id]
-	if !ok { return nil,EInvalidGroup }
-	pub := new(big.Int).Exp(g.G,ex,g.P)
-	return pub,nil
+// Accepts elements in [2,P-2] of the subgroup of order Q. This rejects 0, 1
+// and P-1, as well as elements of small subgroups.
+func (g *linearGroup) Validate(p Element) error {
+	x := p.(*big.Int)
+	one := big.NewInt(1)
+	pm1 := new(big.Int).Sub(g.P,one)
+	if x.Cmp(one)==0 { return EIdentityElement }
+	if x.Sign()<=0 || x.Cmp(pm1)>=0 { return EKeyOutOfRange }
+	/*
+	Older versions used the 2048-bit prime of group 14 for group 17. Elements of
+	the 6144-bit group are below 2^2048 with negligible probability, so these
+	are keys of the old parameters.
+	*/
+	if g==linearGroups[17] && x.BitLen()<=2048 { return EObsoleteKey }
+	if new(big.Int).Exp(x,g.Q,g.P).Cmp(one)!=0 { return ESmallSubgroup }
+	return nil
 }
 
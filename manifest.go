@@ -0,0 +1,187 @@
+/*
+MIT License
+
+Copyright (c) 2017 Simon Schmidt
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+
+package generalcryptosystem
+
+import "io"
+import "os"
+import "time"
+import "hash"
+import "bytes"
+import "path/filepath"
+import "encoding/asn1"
+import "golang.org/x/crypto/blake2b"
+
+/*
+A manifest signature is a detached signature over a file, that binds the name,
+the size and the time of signing of the file and a context string, chosen by
+the application (such as "release" or the name of a project), to its content.
+A signature for one file can not be used for a file with another name or size,
+or in another context.
+
+The transcript hash is BLAKE2b-512 keyed with sigLabelManifest, over the DER
+encoding of the Manifest followed by the content. As plain signatures use the
+unkeyed hash, neither kind of signature can be taken for the other. The scheme
+is SigCompact.
+
+The signature file (MarshalManifestSignature) is an armored block of the type
+ArmorManifest, holding
+
+	SEQUENCE {
+		manifest  SEQUENCE { name UTF8String, size INTEGER, time GeneralizedTime, context UTF8String }
+		signature OCTET STRING (see Signature.MarshalBinary)
+	}
+*/
+
+const sigLabelManifest = "generalcryptosystem manifest"
+
+const ArmorManifest = "GCS MANIFEST SIGNATURE"
+
+// The file metadata bound into a manifest signature.
+type Manifest struct{
+	Name    string    `asn1:"utf8"`        /* The base name of the file */
+	Size    int64                          /* The size of the file in bytes */
+	Time    time.Time `asn1:"generalized"` /* The time of signing, in seconds */
+	Context string    `asn1:"utf8"`        /* Chosen by the application */
+}
+
+type manifestFile struct{
+	Manifest  Manifest
+	Signature []byte
+}
+
+// Returns the manifest as it is stored: the time in seconds, in UTC.
+func (m *Manifest) normalized() Manifest {
+	c := *m
+	c.Time = m.Time.UTC().Truncate(time.Second)
+	return c
+}
+
+// Returns the transcript hash with the manifest written to it.
+func manifestHash(m *Manifest) (hash.Hash,error) {
+	b,e := asn1.Marshal(m.normalized())
+	if e!=nil { return nil,e }
+	h,_ := blake2b.New512([]byte(sigLabelManifest))
+	h.Write(b)
+	return h,nil
+}
+
+type manifestVerifier struct{
+	Verifier
+	size int64
+	n    int64
+}
+func (v *manifestVerifier) Write(p []byte) (int,error) {
+	v.n += int64(len(p))
+	return v.Verifier.Write(p)
+}
+func (v *manifestVerifier) Verify() bool {
+	return v.Verifier.Verify() && v.n==v.size
+}
+
+// Creates a Signer for the content of the file described by m. The time is
+// stored in seconds, in UTC.
+func SignManifest(priv *PrivateKey, m *Manifest, r io.Reader) (Signer,error) {
+	h,e := manifestHash(m)
+	if e!=nil { return nil,e }
+	return newSigner(priv,r,h)
+}
+
+// Creates a Verifier for the content of the file described by m. Verify fails,
+// if the number of bytes written differs from m.Size.
+func VerifyManifest(pub *PublicKey, m *Manifest, sig *Signature) (Verifier,error) {
+	if sig.Version!=SigCompact { return nil,EUnsupportedVersion }
+	h,e := manifestHash(m)
+	if e!=nil { return nil,e }
+	v,e := newVerifier(pub,sig,h)
+	if e!=nil { return nil,e }
+	return &manifestVerifier{v,m.Size,0},nil
+}
+
+// Encodes the manifest and its signature as signature file.
+func MarshalManifestSignature(m *Manifest, sig *Signature) ([]byte,error) {
+	sb,e := sig.MarshalBinary()
+	if e!=nil { return nil,e }
+	b,e := asn1.Marshal(manifestFile{m.normalized(),sb})
+	if e!=nil { return nil,e }
+	var buf bytes.Buffer
+	w := NewArmorWriter(&buf,ArmorManifest)
+	w.Write(b)
+	w.Close()
+	return buf.Bytes(),nil
+}
+
+// Parses a signature file.
+func ParseManifestSignature(data []byte) (*Manifest,*Signature,error) {
+	b,e := io.ReadAll(NewArmorReader(bytes.NewReader(data),ArmorManifest))
+	if e!=nil { return nil,nil,e }
+	var f manifestFile
+	rest,e := asn1.Unmarshal(b,&f)
+	if e!=nil || len(rest)!=0 { return nil,nil,EInvalidSignature }
+	sig := new(Signature)
+	if e = sig.UnmarshalBinary(f.Signature); e!=nil { return nil,nil,e }
+	return &f.Manifest,sig,nil
+}
+
+// Signs the file at path, with the given context, and returns the signature
+// file. The manifest holds the base name of path and the current time.
+func SignFile(priv *PrivateKey, path, context string, r io.Reader) ([]byte,error) {
+	f,e := os.Open(path)
+	if e!=nil { return nil,e }
+	defer f.Close()
+	fi,e := f.Stat()
+	if e!=nil { return nil,e }
+	m := &Manifest{filepath.Base(path),fi.Size(),time.Now(),context}
+	s,e := SignManifest(priv,m,r)
+	if e!=nil { return nil,e }
+	n,e := io.Copy(s,f)
+	if e!=nil { return nil,e }
+	/* The file changed while it was read. */
+	if n!=m.Size { return nil,EManifestMismatch }
+	sig := s.Sign()
+	if sig==nil { return nil,EInvalidGroup }
+	return MarshalManifestSignature(m,sig)
+}
+
+// Verifies the signature file for the file at path. The base name of path, the
+// size of the file and the context must match the manifest, otherwise it fails
+// with EManifestMismatch. An invalid signature gives EInvalidSignature. On
+// success, it returns the manifest, that holds the time of signing.
+func VerifyFile(pub *PublicKey, path, context string, data []byte) (*Manifest,error) {
+	m,sig,e := ParseManifestSignature(data)
+	if e!=nil { return nil,e }
+	f,e := os.Open(path)
+	if e!=nil { return nil,e }
+	defer f.Close()
+	fi,e := f.Stat()
+	if e!=nil { return nil,e }
+	if m.Name!=filepath.Base(path) || m.Size!=fi.Size() || m.Context!=context { return nil,EManifestMismatch }
+	v,e := VerifyManifest(pub,m,sig)
+	if e!=nil { return nil,e }
+	_,e = io.Copy(v,f)
+	if e!=nil { return nil,e }
+	if !v.Verify() { return nil,EInvalidSignature }
+	return m,nil
+}
@@ -0,0 +1,75 @@
+/*
+MIT License
+
+Copyright (c) 2017 Simon Schmidt
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+
+package generalcryptosystem
+
+import "os"
+import "time"
+import "testing"
+import "crypto/rand"
+import "path/filepath"
+
+func TestManifest(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir,"app-1.0.tar")
+	p2 := filepath.Join(dir,"app-2.0.tar")
+	os.WriteFile(p,[]byte("artifact contents"),0644)
+	os.WriteFile(p2,[]byte("artifact contents"),0644)
+	pub,priv := testKeys(t,Ed25519)
+	sf,e := SignFile(priv,p,"release",rand.Reader)
+	if e!=nil { t.Fatal(e) }
+	m,e := VerifyFile(pub,p,"release",sf)
+	if e!=nil || m.Name!="app-1.0.tar" || m.Size!=17 || time.Since(m.Time)>time.Minute { t.Fatal(e,m) }
+	if _,e = VerifyFile(pub,p,"nightly",sf); e!=EManifestMismatch { t.Fatal("context",e) }
+	if _,e = VerifyFile(pub,p2,"release",sf); e!=EManifestMismatch { t.Fatal("name",e) }
+	other,_ := testKeys(t,Ed25519)
+	if _,e = VerifyFile(other,p,"release",sf); e!=EInvalidSignature { t.Fatal("key",e) }
+	os.WriteFile(p,[]byte("artifact Contents"),0644)
+	if _,e = VerifyFile(pub,p,"release",sf); e!=EInvalidSignature { t.Fatal("contents",e) }
+	os.WriteFile(p,[]byte("artifact contents"),0644)
+	
+	/* The manifest is covered by the signature. */
+	m,sig,e := ParseManifestSignature(sf)
+	if e!=nil { t.Fatal(e) }
+	m.Name = "app-2.0.tar"
+	forged,_ := MarshalManifestSignature(m,sig)
+	if _,e = VerifyFile(pub,p2,"release",forged); e!=EInvalidSignature { t.Fatal("forged",e) }
+	
+	/* Manifest signatures and plain signatures are not interchangeable. */
+	if verifies(t,pub,sig,[]byte("artifact contents")) { t.Fatal("manifest signature accepted as plain") }
+	m.Name = "app-1.0.tar"
+	mv,_ := VerifyManifest(pub,m,signBytes(t,priv,nil,[]byte("artifact contents")))
+	mv.Write([]byte("artifact contents"))
+	if mv.Verify() { t.Fatal("plain signature accepted as manifest") }
+	
+	/* The size is checked. */
+	ms,_ := SignManifest(priv,&Manifest{"x",3,time.Now(),""},nil)
+	ms.Write([]byte("abcd"))
+	if sg := ms.Sign(); sg!=nil {
+		vv,_ := VerifyManifest(pub,&Manifest{"x",3,time.Now(),""},sg)
+		vv.Write([]byte("abcd"))
+		if vv.Verify() { t.Fatal("size") }
+	}
+}
@@ -0,0 +1,198 @@
+/*
+MIT License
+
+Copyright (c) 2017 Simon Schmidt
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+
+package generalcryptosystem
+
+import "math/big"
+import "encoding/asn1"
+
+/*
+Every group with a registered ASN.1 object identifier is mapped to it: the
+curves to their names in RFC 5480, SEC 2 and RFC 5639, Ed25519 and X25519 to
+the algorithms of RFC 8410. The ModP groups of RFC 3526 and RFC 7919, the
+complex groups and ristretto255 have no object identifier, and neither have
+registered groups. These keep their ObjectID.
+
+Headers and keys store the object identifier of the group where there is one,
+the ObjectID otherwise. Decoding accepts both forms, so keys written with the
+ObjectID form (by asn1.Marshal of a PublicKey) stay readable.
+*/
+
+var groupOIDs = map[Group]asn1.ObjectIdentifier{
+	FIPS_P224: {1,3,132,0,33},
+	FIPS_P256: {1,2,840,10045,3,1,7},
+	FIPS_P384: {1,3,132,0,34},
+	FIPS_P521: {1,3,132,0,35},
+	
+	Koblitz_S160: {1,3,132,0,9},
+	Koblitz_S192: {1,3,132,0,31},
+	Koblitz_S224: {1,3,132,0,32},
+	Koblitz_S256: {1,3,132,0,10},
+	
+	Brainpool_P160r1: {1,3,36,3,3,2,8,1,1,1},
+	Brainpool_P160t1: {1,3,36,3,3,2,8,1,1,2},
+	Brainpool_P192r1: {1,3,36,3,3,2,8,1,1,3},
+	Brainpool_P192t1: {1,3,36,3,3,2,8,1,1,4},
+	Brainpool_P224r1: {1,3,36,3,3,2,8,1,1,5},
+	Brainpool_P224t1: {1,3,36,3,3,2,8,1,1,6},
+	Brainpool_P256r1: {1,3,36,3,3,2,8,1,1,7},
+	Brainpool_P256t1: {1,3,36,3,3,2,8,1,1,8},
+	Brainpool_P320r1: {1,3,36,3,3,2,8,1,1,9},
+	Brainpool_P320t1: {1,3,36,3,3,2,8,1,1,10},
+	Brainpool_P384r1: {1,3,36,3,3,2,8,1,1,11},
+	Brainpool_P384t1: {1,3,36,3,3,2,8,1,1,12},
+	Brainpool_P512r1: {1,3,36,3,3,2,8,1,1,13},
+	Brainpool_P512t1: {1,3,36,3,3,2,8,1,1,14},
+	
+	Ed25519: {1,3,101,112},
+	X25519:  {1,3,101,110},
+}
+
+// Returns the object identifier of the group, or nil if it has none.
+func (g Group) OID() asn1.ObjectIdentifier {
+	return groupOIDs[g]
+}
+
+// Returns the group with the given object identifier.
+func GroupByOID(oid asn1.ObjectIdentifier) (Group,bool) {
+	for g,o := range groupOIDs {
+		if o.Equal(oid) { return g,true }
+	}
+	return 0,false
+}
+
+// Returns the built-in group with the given identifier.
+func groupOf(id ObjectID) (Group,bool) {
+	for g,gid := range groups {
+		if gid.key()==id.key() { return g,true }
+	}
+	return 0,false
+}
+
+// Returns the object identifier of the group, or nil if it has none.
+func (id ObjectID) OID() asn1.ObjectIdentifier {
+	if g,ok := groupOf(id); ok { return g.OID() }
+	return nil
+}
+
+// Encodes the identifier of the group for headers and keys: its object
+// identifier if it has one, the ObjectID as SEQUENCE OF INTEGER otherwise.
+func marshalGroupID(id ObjectID) (asn1.RawValue,error) {
+	var b []byte
+	var e error
+	if oid := id.OID(); oid!=nil {
+		b,e = asn1.Marshal(oid)
+	}else{
+		b,e = asn1.Marshal(id)
+	}
+	return asn1.RawValue{FullBytes:b},e
+}
+
+// Decodes the identifier of the group, in either form. Unknown object
+// identifiers are rejected with EInvalidGroup, unknown ObjectIDs are not.
+func parseGroupID(raw asn1.RawValue) (ObjectID,error) {
+	b := raw.FullBytes
+	if len(b)==0 { return nil,EInvalidGroup }
+	if b[0]==asn1.TagOID {
+		var oid asn1.ObjectIdentifier
+		rest,e := asn1.Unmarshal(b,&oid)
+		if e!=nil { return nil,e }
+		if len(rest)!=0 { return nil,EInvalidGroup }
+		g,ok := GroupByOID(oid)
+		if !ok { return nil,EInvalidGroup }
+		return g.ID(),nil
+	}
+	var id ObjectID
+	rest,e := asn1.Unmarshal(b,&id)
+	if e!=nil { return nil,e }
+	if len(rest)!=0 || len(id)==0 { return nil,EInvalidGroup }
+	return id,nil
+}
+
+// The stored form of a PublicKey.
+type publicKeyData struct{
+	Group asn1.RawValue
+	X,Y *big.Int
+	Z []byte
+}
+
+func newPublicKeyData(pub *PublicKey) (*publicKeyData,error) {
+	gid,e := marshalGroupID(pub.Group)
+	if e!=nil { return nil,e }
+	return &publicKeyData{gid,pub.X,pub.Y,pub.Z},nil
+}
+
+func (d *publicKeyData) key() (*PublicKey,error) {
+	id,e := parseGroupID(d.Group)
+	if e!=nil { return nil,e }
+	return &PublicKey{id,d.X,d.Y,d.Z},nil
+}
+
+// Encodes the public key in ASN.1, with the object identifier of the group.
+func (pub *PublicKey) MarshalBinary() ([]byte,error) {
+	d,e := newPublicKeyData(pub)
+	if e!=nil { return nil,e }
+	return asn1.Marshal(*d)
+}
+
+// Decodes the output of MarshalBinary, as well as the output of asn1.Marshal
+// of a PublicKey.
+func (pub *PublicKey) UnmarshalBinary(b []byte) error {
+	var d publicKeyData
+	rest,e := asn1.Unmarshal(b,&d)
+	if e!=nil { return e }
+	if len(rest)!=0 { return EInvalidEncoding }
+	k,e := d.key()
+	if e!=nil { return e }
+	*pub = *k
+	return nil
+}
+
+// The stored form of a PrivateKey.
+type privateKeyData struct{
+	Group asn1.RawValue
+	Secret *big.Int
+	Seed []byte `asn1:"optional"`
+}
+
+// Encodes the private key in ASN.1, with the object identifier of the group.
+func (priv *PrivateKey) MarshalBinary() ([]byte,error) {
+	gid,e := marshalGroupID(priv.Group)
+	if e!=nil { return nil,e }
+	return asn1.Marshal(privateKeyData{gid,priv.Secret,priv.Seed})
+}
+
+// Decodes the output of MarshalBinary, as well as the output of asn1.Marshal
+// of a PrivateKey.
+func (priv *PrivateKey) UnmarshalBinary(b []byte) error {
+	var d privateKeyData
+	rest,e := asn1.Unmarshal(b,&d)
+	if e!=nil { return e }
+	if len(rest)!=0 { return EInvalidEncoding }
+	id,e := parseGroupID(d.Group)
+	if e!=nil { return e }
+	*priv = PrivateKey{id,d.Secret,d.Seed}
+	return nil
+}
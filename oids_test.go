@@ -0,0 +1,74 @@
+/*
+MIT License
+
+Copyright (c) 2017 Simon Schmidt
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+
+package generalcryptosystem
+
+import "bytes"
+import "testing"
+import "encoding/asn1"
+
+func TestGroupOIDs(t *testing.T) {
+	for g := range groups {
+		if o := g.OID(); o!=nil {
+			if g2,ok := GroupByOID(o); !ok || g2!=g { t.Fatal(g) }
+		}
+	}
+	if g,ok := GroupByOID(asn1.ObjectIdentifier{1,2,840,10045,3,1,7}); !ok || g!=FIPS_P256 { t.Fatal("P-256") }
+	if Koblitz_S256.OID().String()!="1.3.132.0.10" { t.Fatal("secp256k1") }
+}
+
+/* Groups with an OID are written as the OID, the others as the ObjectID. */
+func TestKeyEncoding(t *testing.T) {
+	for _,g := range testGroups {
+		pub,priv := testKeys(t,g)
+		b,e := pub.MarshalBinary()
+		if e!=nil { t.Fatal(g,e) }
+		var raw struct{ G asn1.RawValue; Rest asn1.RawValue `asn1:"optional"` }
+		if _,e = asn1.Unmarshal(b,&raw); e!=nil { t.Fatal(g,e) }
+		if (raw.G.Tag==asn1.TagOID)!=(g.OID()!=nil) { t.Fatal(g,"group form",raw.G.Tag) }
+		pub2 := new(PublicKey)
+		if e = pub2.UnmarshalBinary(b); e!=nil || !pub2.Equal(pub) { t.Fatal(g,e) }
+		b,e = priv.MarshalBinary()
+		if e!=nil { t.Fatal(g,e) }
+		priv2 := new(PrivateKey)
+		if e = priv2.UnmarshalBinary(b); e!=nil || !priv2.Equal(priv) { t.Fatal(g,e) }
+		
+		/* The envelope header names the group in the same form. */
+		if g.OID()!=nil && !bytes.Contains(encryptBytes(t,[]*PublicKey{pub},nil,nil),raw.G.FullBytes) { t.Fatal(g,"header lacks the OID") }
+	}
+}
+
+/* Keys written by asn1.Marshal before the OID form. */
+func TestKeyEncodingBaseline(t *testing.T) {
+	for _,name := range baselineNames {
+		pub,priv := readBaseline(t,name)
+		b,_ := pub.MarshalBinary()
+		pub2 := new(PublicKey)
+		if e := pub2.UnmarshalBinary(b); e!=nil || !pub2.Equal(pub) { t.Fatal(name,e) }
+		b,_ = priv.MarshalBinary()
+		priv2 := new(PrivateKey)
+		if e := priv2.UnmarshalBinary(b); e!=nil || !priv2.Equal(priv) { t.Fatal(name,e) }
+	}
+}
@@ -0,0 +1,313 @@
+/*
+MIT License
+
+Copyright (c) 2017 Simon Schmidt
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+
+package generalcryptosystem
+
+import "math/big"
+import "encoding/asn1"
+import "encoding/pem"
+import "crypto/x509/pkix"
+
+/*
+Public keys are encoded as X.509 SubjectPublicKeyInfo (RFC 5280) and private
+keys as PKCS #8 (RFC 5208), so they can be read by OpenSSL. crypto/x509 only
+reads the keys of the NIST curves (P-224 to P-521), Ed25519 and X25519, and
+rejects dhpublicnumber, the Koblitz curves (including secp256k1) and the
+Brainpool curves.
+
+The named curves use id-ecPublicKey with the curve OID (see oids.go) as
+parameter and the uncompressed point. Private keys hold an ECPrivateKey
+(RFC 5915).
+
+Ed25519 and X25519 use the algorithms of RFC 8410. The private key is the seed
+(see PrivateKey.Seed), so only keys made by GenerateKeyPair or parsed from
+PKCS #8 can be exported.
+
+The ModP groups use dhpublicnumber with the domain parameters p, g and q
+(ANSI X9.42, RFC 3279). Parsing recognizes the built-in and registered ModP
+groups by their parameters.
+
+The complex groups, ristretto255 and other registered groups have no standard
+encoding. These fail with EUnsupportedGroup.
+*/
+
+var (
+	oidPublicKeyEC = asn1.ObjectIdentifier{1,2,840,10045,2,1}
+	oidPublicKeyDH = asn1.ObjectIdentifier{1,2,840,10046,2,1}
+)
+
+type publicKeyInfo struct{
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+type pkcs8 struct{
+	Version    int
+	Algorithm  pkix.AlgorithmIdentifier
+	PrivateKey []byte
+	/* Attributes and the public key of RFC 5958 are not read. */
+}
+
+type ecPrivateKey struct{
+	Version    int
+	PrivateKey []byte
+	Parameters asn1.ObjectIdentifier `asn1:"optional,explicit,tag:0"`
+	PublicKey  asn1.BitString `asn1:"optional,explicit,tag:1"`
+}
+
+type dhParameters struct{
+	P,G,Q *big.Int
+}
+
+// Returns the identifier of the built-in or registered ModP group with the
+// given parameters, or nil.
+func modpGroupOf(p *dhParameters) ObjectID {
+	match := func(g *linearGroup) bool {
+		return g.P.Cmp(p.P)==0 && g.G.Cmp(p.G)==0 && g.Q.Cmp(p.Q)==0
+	}
+	for n,g := range linearGroups {
+		if match(g) { return ObjectID{group_ModP,n} }
+	}
+	registry.RLock()
+	defer registry.RUnlock()
+	for _,r := range registry.byID {
+		if g,ok := r.impl.(*linearGroup); ok && match(g) { return r.id }
+	}
+	return nil
+}
+
+// Returns the algorithm identifier for keys of the group.
+func keyAlgorithm(group ObjectID, g GroupImpl) (pkix.AlgorithmIdentifier,error) {
+	var ai pkix.AlgorithmIdentifier
+	if lg,ok := g.(*linearGroup); ok {
+		b,e := asn1.Marshal(dhParameters{lg.P,lg.G,lg.Q})
+		if e!=nil { return ai,e }
+		ai.Algorithm = oidPublicKeyDH
+		ai.Parameters = asn1.RawValue{FullBytes:b}
+		return ai,nil
+	}
+	oid := group.OID()
+	if oid==nil { return ai,EUnsupportedGroup }
+	if _,ok := g.(*curveGroup); !ok {
+		/* Ed25519 and X25519 */
+		ai.Algorithm = oid
+		return ai,nil
+	}
+	b,e := asn1.Marshal(oid)
+	if e!=nil { return ai,e }
+	ai.Algorithm = oidPublicKeyEC
+	ai.Parameters = asn1.RawValue{FullBytes:b}
+	return ai,nil
+}
+
+// Returns the group of the algorithm identifier.
+func parseKeyAlgorithm(ai pkix.AlgorithmIdentifier) (ObjectID,GroupImpl,error) {
+	var id ObjectID
+	switch {
+	case ai.Algorithm.Equal(oidPublicKeyEC):
+		var oid asn1.ObjectIdentifier
+		rest,e := asn1.Unmarshal(ai.Parameters.FullBytes,&oid)
+		if e!=nil || len(rest)!=0 { return nil,nil,EInvalidEncoding }
+		if g,ok := GroupByOID(oid); ok && getCurve(g.ID())!=nil { id = g.ID() }
+	case ai.Algorithm.Equal(oidPublicKeyDH):
+		p := new(dhParameters)
+		rest,e := asn1.Unmarshal(ai.Parameters.FullBytes,p)
+		if e!=nil || len(rest)!=0 { return nil,nil,EInvalidEncoding }
+		id = modpGroupOf(p)
+	default:
+		/* Ed25519 and X25519 */
+		if g,ok := GroupByOID(ai.Algorithm); ok && getCurve(g.ID())==nil {
+			if len(ai.Parameters.FullBytes)!=0 { return nil,nil,EInvalidEncoding }
+			id = g.ID()
+		}
+	}
+	if id==nil { return nil,nil,EUnsupportedGroup }
+	return id,getGroupImpl(id),nil
+}
+
+// Encodes the public key as X.509 SubjectPublicKeyInfo (DER).
+func MarshalPKIXPublicKey(pub *PublicKey) ([]byte,error) {
+	g := getGroupImpl(pub.Group)
+	if g==nil { return nil,EInvalidGroup }
+	ai,e := keyAlgorithm(pub.Group,g)
+	if e!=nil { return nil,e }
+	P,e := g.KeyElement(pub)
+	if e!=nil { return nil,e }
+	var b []byte
+	switch g.(type) {
+	case *linearGroup:
+		b,e = asn1.Marshal(P)
+		if e!=nil { return nil,e }
+	case *curveGroup:
+		b = append([]byte{4},g.Encode(P)...)
+	default:
+		b = g.Encode(P)
+	}
+	return asn1.Marshal(publicKeyInfo{ai,asn1.BitString{Bytes:b,BitLength:8*len(b)}})
+}
+
+// Parses a X.509 SubjectPublicKeyInfo (DER). The key is validated.
+func ParsePKIXPublicKey(der []byte) (*PublicKey,error) {
+	var info publicKeyInfo
+	rest,e := asn1.Unmarshal(der,&info)
+	if e!=nil { return nil,e }
+	if len(rest)!=0 { return nil,EInvalidEncoding }
+	id,g,e := parseKeyAlgorithm(info.Algorithm)
+	if e!=nil { return nil,e }
+	b := info.PublicKey.RightAlign()
+	var P Element
+	switch g.(type) {
+	case *linearGroup:
+		y := new(big.Int)
+		rest,e = asn1.Unmarshal(b,&y)
+		if e!=nil || len(rest)!=0 { return nil,EInvalidEncoding }
+		P = y
+	case *curveGroup:
+		/* Only the uncompressed form is supported. */
+		if len(b)==0 || b[0]!=4 { return nil,EInvalidEncoding }
+		P,e = g.Decode(b[1:])
+	default:
+		P,e = g.Decode(b)
+	}
+	if e!=nil { return nil,EInvalidEncoding }
+	if e = g.Validate(P); e!=nil { return nil,e }
+	pub := &PublicKey{Group:id}
+	g.SetKeyElement(pub,P)
+	return pub,nil
+}
+
+// Encodes the private key as PKCS #8 (DER).
+func MarshalPKCS8PrivateKey(priv *PrivateKey) ([]byte,error) {
+	g := getGroupImpl(priv.Group)
+	if g==nil { return nil,EInvalidGroup }
+	if priv.Secret==nil { return nil,EInvalidKey }
+	ai,e := keyAlgorithm(priv.Group,g)
+	if e!=nil { return nil,e }
+	var b []byte
+	switch g.(type) {
+	case *linearGroup:
+		b,e = asn1.Marshal(priv.Secret)
+	case *curveGroup:
+		n := g.Order()
+		if priv.Secret.Sign()<=0 || priv.Secret.Cmp(n)>=0 { return nil,EInvalidKey }
+		pk := append([]byte{4},g.Encode(g.BaseMult(priv.Secret))...)
+		b,e = asn1.Marshal(ecPrivateKey{
+			Version:1,
+			PrivateKey:priv.Secret.FillBytes(make([]byte,(n.BitLen()+7)/8)),
+			PublicKey:asn1.BitString{Bytes:pk,BitLength:8*len(pk)},
+		})
+	default:
+		sg,ok := g.(seededGroup)
+		if !ok || len(priv.Seed)!=32 { return nil,EUnsupportedGroup }
+		if sg.secretFromSeed(priv.Seed).Cmp(priv.Secret)!=0 { return nil,EInvalidKey }
+		b,e = asn1.Marshal(priv.Seed)
+	}
+	if e!=nil { return nil,e }
+	return asn1.Marshal(pkcs8{0,ai,b})
+}
+
+// Parses a PKCS #8 private key (DER).
+func ParsePKCS8PrivateKey(der []byte) (*PrivateKey,error) {
+	var p8 pkcs8
+	rest,e := asn1.Unmarshal(der,&p8)
+	if e!=nil { return nil,e }
+	if len(rest)!=0 { return nil,EInvalidEncoding }
+	if p8.Version!=0 && p8.Version!=1 { return nil,EUnsupportedVersion }
+	id,g,e := parseKeyAlgorithm(p8.Algorithm)
+	if e!=nil { return nil,e }
+	priv := &PrivateKey{Group:id}
+	n := g.Order()
+	switch g.(type) {
+	case *linearGroup:
+		x := new(big.Int)
+		rest,e = asn1.Unmarshal(p8.PrivateKey,&x)
+		if e!=nil || len(rest)!=0 { return nil,EInvalidEncoding }
+		priv.Secret = x
+	case *curveGroup:
+		var ek ecPrivateKey
+		rest,e = asn1.Unmarshal(p8.PrivateKey,&ek)
+		if e!=nil || len(rest)!=0 || ek.Version!=1 { return nil,EInvalidEncoding }
+		if ek.Parameters!=nil {
+			/* Already checked by parseKeyAlgorithm. */
+			var oid asn1.ObjectIdentifier
+			asn1.Unmarshal(p8.Algorithm.Parameters.FullBytes,&oid)
+			if !oid.Equal(ek.Parameters) { return nil,EGroupMismatch }
+		}
+		priv.Secret = new(big.Int).SetBytes(ek.PrivateKey)
+	default:
+		sg,ok := g.(seededGroup)
+		if !ok { return nil,EUnsupportedGroup }
+		var seed []byte
+		rest,e = asn1.Unmarshal(p8.PrivateKey,&seed)
+		if e!=nil || len(rest)!=0 || len(seed)!=32 { return nil,EInvalidEncoding }
+		priv.Seed = seed
+		priv.Secret = sg.secretFromSeed(seed)
+	}
+	if priv.Secret.Sign()<=0 || priv.Secret.Cmp(n)>=0 { return nil,EInvalidKey }
+	return priv,nil
+}
+
+const (
+	pemPublicKey  = "PUBLIC KEY"
+	pemPrivateKey = "PRIVATE KEY"
+)
+
+// Returns the first PEM block of the given type.
+func decodePEM(data []byte, typ string) ([]byte,error) {
+	for {
+		var b *pem.Block
+		b,data = pem.Decode(data)
+		if b==nil { return nil,EInvalidEncoding }
+		if b.Type==typ { return b.Bytes,nil }
+	}
+}
+
+// Encodes the public key as a PEM block of the type "PUBLIC KEY".
+func MarshalPublicKeyPEM(pub *PublicKey) ([]byte,error) {
+	der,e := MarshalPKIXPublicKey(pub)
+	if e!=nil { return nil,e }
+	return pem.EncodeToMemory(&pem.Block{Type:pemPublicKey,Bytes:der}),nil
+}
+
+// Parses the first "PUBLIC KEY" PEM block in data.
+func ParsePublicKeyPEM(data []byte) (*PublicKey,error) {
+	der,e := decodePEM(data,pemPublicKey)
+	if e!=nil { return nil,e }
+	return ParsePKIXPublicKey(der)
+}
+
+// Encodes the private key as a PEM block of the type "PRIVATE KEY".
+func MarshalPrivateKeyPEM(priv *PrivateKey) ([]byte,error) {
+	der,e := MarshalPKCS8PrivateKey(priv)
+	if e!=nil { return nil,e }
+	return pem.EncodeToMemory(&pem.Block{Type:pemPrivateKey,Bytes:der}),nil
+}
+
+// Parses the first "PRIVATE KEY" PEM block in data.
+func ParsePrivateKeyPEM(data []byte) (*PrivateKey,error) {
+	der,e := decodePEM(data,pemPrivateKey)
+	if e!=nil { return nil,e }
+	return ParsePKCS8PrivateKey(der)
+}
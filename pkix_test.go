@@ -0,0 +1,105 @@
+/*
+MIT License
+
+Copyright (c) 2017 Simon Schmidt
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+
+package generalcryptosystem
+
+import "os"
+import "bytes"
+import "testing"
+import "crypto/x509"
+import "crypto/ecdsa"
+import "crypto/ecdh"
+import "crypto/ed25519"
+
+func TestPKIX(t *testing.T) {
+	for _,g := range []Group{Modp14,FFDHE2048,FIPS_P224,FIPS_P256,FIPS_P521,Koblitz_S160,Koblitz_S256,Brainpool_P256r1,Brainpool_P320t1,Ed25519,X25519} {
+		pub,priv := testKeys(t,g)
+		pp,e := MarshalPublicKeyPEM(pub)
+		if e!=nil { t.Fatal(g,e) }
+		sp,e := MarshalPrivateKeyPEM(priv)
+		if e!=nil { t.Fatal(g,e) }
+		pub2,e := ParsePublicKeyPEM(pp)
+		if e!=nil || !pub2.Equal(pub) { t.Fatal(g,e) }
+		priv2,e := ParsePrivateKeyPEM(sp)
+		if e!=nil || priv2.Secret.Cmp(priv.Secret)!=0 || !priv2.PublicKey().Equal(pub) { t.Fatal(g,e) }
+		a,_ := MarshalPKCS8PrivateKey(priv)
+		b,_ := MarshalPKCS8PrivateKey(priv2)
+		if !bytes.Equal(a,b) { t.Fatal(g,"PKCS#8 differs") }
+	}
+	_,priv := testKeys(t,Ristretto255)
+	if _,e := MarshalPKCS8PrivateKey(priv); e!=EUnsupportedGroup { t.Fatal(e) }
+	pub,_ := testKeys(t,Complex_2048bit)
+	if _,e := MarshalPKIXPublicKey(pub); e!=EUnsupportedGroup { t.Fatal(e) }
+}
+
+/* The keys crypto/x509 can read, in both directions. */
+func TestPKIXStdlib(t *testing.T) {
+	for _,g := range []Group{FIPS_P224,FIPS_P256,FIPS_P384,FIPS_P521,Ed25519,X25519} {
+		pub,priv := testKeys(t,g)
+		der,e := MarshalPKIXPublicKey(pub)
+		if e!=nil { t.Fatal(g,e) }
+		kd,e := MarshalPKCS8PrivateKey(priv)
+		if e!=nil { t.Fatal(g,e) }
+		k,e := x509.ParsePKIXPublicKey(der)
+		if e!=nil { t.Fatal(g,e) }
+		s,e := x509.ParsePKCS8PrivateKey(kd)
+		if e!=nil { t.Fatal(g,e) }
+		switch k := k.(type) {
+		case *ecdsa.PublicKey:
+			if k.X.Cmp(pub.X)!=0 || k.Y.Cmp(pub.Y)!=0 || s.(*ecdsa.PrivateKey).D.Cmp(priv.Secret)!=0 { t.Fatal(g) }
+		case ed25519.PublicKey:
+			if !bytes.Equal(k,pub.Z) || !bytes.Equal(s.(ed25519.PrivateKey).Public().(ed25519.PublicKey),pub.Z) { t.Fatal(g) }
+		case *ecdh.PublicKey:
+			if !bytes.Equal(k.Bytes(),pub.Z) || !bytes.Equal(s.(*ecdh.PrivateKey).PublicKey().Bytes(),pub.Z) { t.Fatal(g) }
+		default:
+			t.Fatal(g,k)
+		}
+		
+		/* Written by crypto/x509. */
+		der,_ = x509.MarshalPKIXPublicKey(k)
+		kd,_ = x509.MarshalPKCS8PrivateKey(s)
+		pub2,e := ParsePKIXPublicKey(der)
+		if e!=nil || !pub2.Equal(pub) { t.Fatal(g,e) }
+		priv2,e := ParsePKCS8PrivateKey(kd)
+		if e!=nil || !priv2.PublicKey().Equal(pub) { t.Fatal(g,e) }
+	}
+}
+
+/* The keys in testdata/openssl were written by "openssl genpkey". */
+func TestPKIXOpenSSL(t *testing.T) {
+	for _,name := range []string{"ed25519","x25519","secp256k1","p384","ffdhe2048"} {
+		d,e := os.ReadFile("testdata/openssl/"+name+".key")
+		if e!=nil { t.Fatal(e) }
+		priv,e := ParsePrivateKeyPEM(d)
+		if e!=nil { t.Fatal(name,e) }
+		d,e = os.ReadFile("testdata/openssl/"+name+".pub")
+		if e!=nil { t.Fatal(e) }
+		pub,e := ParsePublicKeyPEM(d)
+		if e!=nil { t.Fatal(name,e) }
+		if e = pub.Validate(); e!=nil || !priv.PublicKey().Equal(pub) { t.Fatal(name,e) }
+		out,e := decryptBytes(priv,encryptBytes(t,[]*PublicKey{pub},nil,[]byte("openssl")))
+		if e!=nil || string(out)!="openssl" { t.Fatal(name,e) }
+	}
+}
@@ -0,0 +1,149 @@
+/*
+MIT License
+
+Copyright (c) 2017 Simon Schmidt
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package generalcryptosystem
+
+import "io"
+import "sync"
+import "crypto/cipher"
+
+/*
+Random access to the plaintext of an envelope. As every segment of the
+segmented stream can be opened on its own (the position and the final flag are
+given by the size of the ciphertext), only the segments covering the requested
+range are read and authenticated.
+
+The RandomAccessReader implements io.ReaderAt, which is safe for concurrent
+use, and io.ReadSeeker, which is not.
+*/
+type RandomAccessReader struct{
+	src   io.ReaderAt
+	aead  cipher.AEAD
+	ad    []byte
+	base  int64
+	nseg  int64
+	last  int64
+	size  int64
+	pos   int64
+	
+	mutex sync.Mutex
+	cseg  int64
+	cbuf  []byte
+	plain []byte
+}
+
+// Decrypts the envelope of size bytes stored in src for random access.
+// The legacy format is not supported; for it, EUnsupportedVersion is returned.
+func DecryptAt(priv *PrivateKey, src io.ReaderAt, size int64) (*RandomAccessReader,error) {
+	sr := io.NewSectionReader(src,0,size)
+	var magic [4]byte
+	_,e := io.ReadFull(sr,magic[:])
+	if e!=nil { return nil,e }
+	if magic!=envelopeMagic { return nil,EUnsupportedVersion }
+	p,e := parseEnvelope(sr)
+	if e!=nil { return nil,e }
+	aead,e := openEnvelope(priv,p)
+	if e!=nil { return nil,e }
+	
+	r := new(RandomAccessReader)
+	r.src  = src
+	r.aead = aead
+	r.ad   = p.ad
+	r.base = int64(len(p.ad))
+	
+	full := int64(segmentSize+aead.Overhead())
+	payload := size-r.base
+	if payload<int64(aead.Overhead()) { return nil,ETruncated }
+	r.nseg = (payload+full-1)/full
+	r.last = payload-(r.nseg-1)*full
+	if r.last<int64(aead.Overhead()) { return nil,ETruncated }
+	
+	r.cseg = -1
+	r.cbuf = make([]byte,full)
+	r.plain = make([]byte,0,segmentSize)
+	
+	/*
+	The overhead of the last segment depends on the Suite (padding), so the
+	last segment is opened to learn the size of the plaintext.
+	*/
+	e = r.segment(r.nseg-1)
+	if e!=nil { return nil,e }
+	r.size = (r.nseg-1)*segmentSize+int64(len(r.plain))
+	return r,nil
+}
+
+// Returns the size of the plaintext.
+func (r *RandomAccessReader) Size() int64 { return r.size }
+
+// Reads and opens segment i into r.plain. The caller must hold r.mutex.
+func (r *RandomAccessReader) segment(i int64) error {
+	if r.cseg==i { return nil }
+	r.cseg = -1
+	l := int64(len(r.cbuf))
+	if i==r.nseg-1 { l = r.last }
+	_,e := r.src.ReadAt(r.cbuf[:l],r.base+i*int64(len(r.cbuf)))
+	if e==io.EOF { e = ETruncated }
+	if e!=nil { return e }
+	r.plain,e = openSegment(r.aead,uint64(i),i==r.nseg-1,r.plain[:0],r.cbuf[:l],r.ad)
+	if e!=nil { return e }
+	r.cseg = i
+	return nil
+}
+
+func (r *RandomAccessReader) ReadAt(p []byte, off int64) (n int, err error) {
+	if off<0 { return 0,EInvalidOffset }
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for len(p)>0 {
+		if off>=r.size { return n,io.EOF }
+		i := off/segmentSize
+		err = r.segment(i)
+		if err!=nil { return }
+		m := copy(p,r.plain[off-i*segmentSize:])
+		p = p[m:]
+		n += m
+		off += int64(m)
+	}
+	return
+}
+
+func (r *RandomAccessReader) Read(p []byte) (n int, err error) {
+	n,err = r.ReadAt(p,r.pos)
+	r.pos += int64(n)
+	if n>0 && err==io.EOF { err = nil }
+	return
+}
+
+func (r *RandomAccessReader) Seek(offset int64, whence int) (int64,error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent: offset += r.pos
+	case io.SeekEnd: offset += r.size
+	default: return 0,EInvalidOffset
+	}
+	if offset<0 { return 0,EInvalidOffset }
+	r.pos = offset
+	return offset,nil
+}
+
@@ -0,0 +1,86 @@
+/*
+MIT License
+
+Copyright (c) 2017 Simon Schmidt
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+
+package generalcryptosystem
+
+import "io"
+import "bytes"
+import "testing"
+import mrand "math/rand"
+
+func TestDecryptAt(t *testing.T) {
+	pub,priv := testKeys(t,FIPS_P256)
+	for _,s := range []Suite{ChaCha20Poly1305,TwofishCBC} {
+		for _,n := range segmentSizes {
+			msg := testMessage(n)
+			ct := encryptBytes(t,[]*PublicKey{pub},&EncryptOptions{Suite:s},msg)
+			r,e := DecryptAt(priv,bytes.NewReader(ct),int64(len(ct)))
+			if e!=nil { t.Fatal(s,n,e) }
+			if r.Size()!=int64(n) { t.Fatal(s,n,"size",r.Size()) }
+			all,e := io.ReadAll(r)
+			if e!=nil || !bytes.Equal(all,msg) { t.Fatal(s,n,e) }
+			for k := 0; k<20 && n>0; k++ {
+				off := mrand.Intn(n)
+				buf := make([]byte,1+mrand.Intn(n-off))
+				m,e := r.ReadAt(buf,int64(off))
+				if m!=len(buf) || (e!=nil && e!=io.EOF) || !bytes.Equal(buf,msg[off:off+m]) { t.Fatal(s,n,off,m,e) }
+			}
+			if _,e = r.ReadAt(make([]byte,1),int64(n)); e!=io.EOF { t.Fatal(s,n,e) }
+			if _,e = r.ReadAt(make([]byte,1),-1); e!=EInvalidOffset { t.Fatal(s,n,e) }
+			if n>0 {
+				r.Seek(-1,io.SeekEnd)
+				b,_ := io.ReadAll(r)
+				if len(b)!=1 || b[0]!=msg[n-1] { t.Fatal(s,n,"seek") }
+			}
+		}
+	}
+}
+
+func TestDecryptAtTampered(t *testing.T) {
+	pub,priv := testKeys(t,FIPS_P256)
+	msg := testMessage(2*segmentSize+1)
+	ct := encryptBytes(t,[]*PublicKey{pub},nil,msg)
+	
+	/* The last segment is opened by DecryptAt. */
+	c := append([]byte(nil),ct...)
+	c[len(c)-1] ^= 1
+	if _,e := DecryptAt(priv,bytes.NewReader(c),int64(len(c))); e!=EAuthFailed { t.Fatal(e) }
+	
+	/* Other segments are opened, when they are read. */
+	c = append([]byte(nil),ct...)
+	c[headerLength(c)] ^= 1
+	r,e := DecryptAt(priv,bytes.NewReader(c),int64(len(c)))
+	if e!=nil { t.Fatal(e) }
+	if _,e = r.ReadAt(make([]byte,1),0); e!=EAuthFailed { t.Fatal(e) }
+	b := make([]byte,10)
+	if _,e = r.ReadAt(b,segmentSize); e!=nil || !bytes.Equal(b,msg[segmentSize:segmentSize+10]) { t.Fatal(e) }
+	
+	/* Cut at a segment boundary. */
+	l := int64(headerLength(ct)+segmentSize+16)
+	if _,e = DecryptAt(priv,bytes.NewReader(ct),l); e!=ETruncated { t.Fatal(e) }
+	
+	c = legacyEncrypt(t,pub,msg)
+	if _,e = DecryptAt(priv,bytes.NewReader(c),int64(len(c))); e!=EUnsupportedVersion { t.Fatal(e) }
+}
@@ -0,0 +1,118 @@
+/*
+MIT License
+
+Copyright (c) 2017 Simon Schmidt
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package generalcryptosystem
+
+import "sync"
+import "strconv"
+import "strings"
+import "math/big"
+import "crypto/elliptic"
+
+/*
+Groups can be registered at runtime, in addition to the built-in ones. A
+registered group is identified by its ObjectID, which must not start with a
+number reserved for the built-in families (up to 63), and by a unique name.
+Registered groups are used the same way as the built-in ones, except that they
+have no Group value.
+*/
+
+// The highest family number reserved for built-in groups.
+const group_Reserved = 63
+
+type registeredGroup struct{
+	name string
+	id   ObjectID
+	impl GroupImpl
+}
+
+var registry struct{
+	sync.RWMutex
+	byID   map[string]*registeredGroup
+	byName map[string]*registeredGroup
+}
+
+func (id ObjectID) key() string {
+	s := make([]string,len(id))
+	for i,n := range id { s[i] = strconv.Itoa(n) }
+	return strings.Join(s,".")
+}
+
+func lookupRegistered(group ObjectID) GroupImpl {
+	registry.RLock()
+	defer registry.RUnlock()
+	if r,ok := registry.byID[group.key()]; ok { return r.impl }
+	return nil
+}
+
+// Returns the identifier of the built-in or registered group with the given
+// name, or nil.
+func GroupByName(name string) ObjectID {
+	for g,n := range groupNames {
+		if n==name { return g.ID() }
+	}
+	registry.RLock()
+	defer registry.RUnlock()
+	if r,ok := registry.byName[name]; ok { return r.id }
+	return nil
+}
+
+// Registers a group with an arbitrary implementation. Fails, if the name or
+// the identifier is already taken, or the identifier is reserved. The
+// implementation is not checked, use RegisterModPGroup or RegisterCurve
+// where possible.
+func RegisterGroup(name string, id ObjectID, impl GroupImpl) error {
+	if name=="" || len(id)<2 || impl==nil { return EInvalidGroup }
+	if id[0]<=group_Reserved || GroupByName(name)!=nil { return EGroupExists }
+	registry.Lock()
+	defer registry.Unlock()
+	k := id.key()
+	if _,ok := registry.byID[k]; ok { return EGroupExists }
+	if _,ok := registry.byName[name]; ok { return EGroupExists }
+	if registry.byID==nil {
+		registry.byID   = make(map[string]*registeredGroup)
+		registry.byName = make(map[string]*registeredGroup)
+	}
+	r := &registeredGroup{name,append(ObjectID(nil),id...),impl}
+	registry.byID[k] = r
+	registry.byName[name] = r
+	return nil
+}
+
+// Registers the subgroup of order Q of the multiplicative group modulo P,
+// generated by G. P and Q must be prime, Q must divide P-1 and G must have
+// the order Q. P must have at least 2048 bits, Q at least 224 bits. Secrets
+// are chosen from [1,Q).
+func RegisterModPGroup(name string, id ObjectID, P, G, Q *big.Int) error {
+	if P==nil || G==nil || Q==nil || checkModP(P,G,Q)!="" { return EInvalidGroup }
+	g := &linearGroup{new(big.Int).Set(P),new(big.Int).Set(G),new(big.Int).Set(Q),0}
+	return RegisterGroup(name,id,g)
+}
+
+// Registers an elliptic curve of prime order. The field and the order must be
+// prime, the base point must be on the curve and have the order N.
+func RegisterCurve(name string, id ObjectID, curve elliptic.Curve) error {
+	if curve==nil || checkCurve(curve)!="" { return EInvalidGroup }
+	return RegisterGroup(name,id,&curveGroup{curve,curve.Params().N,true})
+}
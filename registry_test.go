@@ -0,0 +1,66 @@
+/*
+MIT License
+
+Copyright (c) 2017 Simon Schmidt
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+
+package generalcryptosystem
+
+import "testing"
+import "math/big"
+import "crypto/rand"
+import "crypto/elliptic"
+
+/* Returns a prime p = k*q+1 of about bits bits, with a generator of order q. */
+func testModP(t *testing.T, bits int) (*big.Int,*big.Int,*big.Int) {
+	q,e := rand.Prime(rand.Reader,256)
+	if e!=nil { t.Fatal(e) }
+	one := big.NewInt(1)
+	for {
+		k,_ := rand.Int(rand.Reader,new(big.Int).Lsh(one,uint(bits-256)))
+		k.Lsh(k,1)
+		p := new(big.Int).Add(new(big.Int).Mul(k,q),one)
+		if p.BitLen()<bits || !p.ProbablyPrime(20) { continue }
+		g := new(big.Int).Exp(big.NewInt(3),k,p)
+		if g.Cmp(one)!=0 { return p,g,q }
+	}
+}
+
+func TestRegister(t *testing.T) {
+	p,g,q := testModP(t,2048)
+	if e := RegisterModPGroup("test-tiny",ObjectID{100,8},big.NewInt(7),big.NewInt(2),big.NewInt(3)); e!=EInvalidGroup { t.Fatal("tiny group",e) }
+	if e := RegisterModPGroup("test-bad",ObjectID{100,9},p,big.NewInt(3),q); e==nil { t.Fatal("bad generator accepted") }
+	if e := RegisterModPGroup("modp14",ObjectID{100,9},p,g,q); e!=EGroupExists { t.Fatal("builtin name",e) }
+	if e := RegisterModPGroup("test-reserved",ObjectID{1,99},p,g,q); e!=EGroupExists { t.Fatal("reserved",e) }
+	if e := RegisterModPGroup("test-modp",ObjectID{100,1},p,g,q); e!=nil { t.Fatal(e) }
+	if e := RegisterCurve("test-modp",ObjectID{100,2},elliptic.P256()); e!=EGroupExists { t.Fatal("duplicate name",e) }
+	if e := RegisterCurve("test-p256",ObjectID{100,2},elliptic.P256()); e!=nil { t.Fatal(e) }
+	for _,name := range []string{"test-modp","test-p256"} {
+		id := GroupByName(name)
+		if id==nil { t.Fatal(name) }
+		pub,priv,e := GenerateKeyPair(id,rand.Reader)
+		if e!=nil { t.Fatal(name,e) }
+		out,e := decryptBytes(priv,encryptBytes(t,[]*PublicKey{pub},nil,[]byte("abc")))
+		if e!=nil || string(out)!="abc" { t.Fatal(name,e) }
+		if !verifies(t,pub,signBytes(t,priv,nil,out),out) { t.Fatal(name,"signature") }
+	}
+}
@@ -0,0 +1,87 @@
+/*
+MIT License
+
+Copyright (c) 2017 Simon Schmidt
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package generalcryptosystem
+
+import "io"
+import "math/big"
+import "crypto/rand"
+import "github.com/gtank/ristretto255"
+
+/*
+The ristretto255 group (RFC 9496) is a group of prime order l, built on top of
+edwards25519. Unlike the curves, it has no cofactor, every valid encoding is an
+element of the group. As with Curve25519, the secret is a scalar in [1,l) and
+the public key is kept in the Z field of PublicKey.
+*/
+
+// Converts a number into a scalar, reducing it modulo l.
+func r255Scalar(k *big.Int) *ristretto255.Scalar {
+	b := new(big.Int).Mod(k,c25519Order).FillBytes(make([]byte,32))
+	for i,j := 0,31; i<j; i,j = i+1,j-1 { b[i],b[j] = b[j],b[i] }
+	s := ristretto255.NewScalar()
+	s.Decode(b)
+	return s
+}
+
+/* GroupImpl. The elements are of type *ristretto255.Element. */
+
+type r255Group struct{}
+
+func (g r255Group) Order() *big.Int { return c25519Order }
+func (g r255Group) GenerateSecret(r io.Reader) (*big.Int,error) {
+	k,e := rand.Int(r,new(big.Int).Sub(c25519Order,big.NewInt(1)))
+	if e!=nil { return nil,e }
+	return k.Add(k,big.NewInt(1)),nil
+}
+func (g r255Group) BaseMult(k *big.Int) Element {
+	return ristretto255.NewElement().ScalarBaseMult(r255Scalar(k))
+}
+func (g r255Group) Mult(k *big.Int, p Element) Element {
+	return ristretto255.NewElement().ScalarMult(r255Scalar(k),p.(*ristretto255.Element))
+}
+func (g r255Group) Add(p, q Element) Element {
+	return ristretto255.NewElement().Add(p.(*ristretto255.Element),q.(*ristretto255.Element))
+}
+func (g r255Group) Encode(p Element) []byte {
+	return p.(*ristretto255.Element).Encode(nil)
+}
+func (g r255Group) Decode(b []byte) (Element,error) {
+	p := ristretto255.NewElement()
+	if len(b)!=32 || p.Decode(b)!=nil { return nil,EKeyOutOfRange }
+	return p,nil
+}
+func (g r255Group) KeyElement(pub *PublicKey) (Element,error) {
+	return g.Decode(pub.Z)
+}
+func (g r255Group) SetKeyElement(pub *PublicKey, p Element) {
+	pub.X = new(big.Int)
+	pub.Y = new(big.Int)
+	pub.Z = g.Encode(p)
+}
+// Every decoded element is in the group, only the identity is rejected.
+func (g r255Group) Validate(p Element) error {
+	if p.(*ristretto255.Element).Equal(ristretto255.NewElement())==1 { return EIdentityElement }
+	return nil
+}
@@ -0,0 +1,214 @@
+/*
+MIT License
+
+Copyright (c) 2017 Simon Schmidt
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package generalcryptosystem
+
+import "fmt"
+import "math/big"
+import "crypto/elliptic"
+
+// Returned by SelfTest.
+type SelfTestError struct{
+	Group  ObjectID
+	Reason string
+}
+func (e *SelfTestError) Error() string {
+	return fmt.Sprintf("self-test failed for group %s: %s",e.Group.key(),e.Reason)
+}
+
+/*
+The standard ModP groups. The primes are defined by
+
+	RFC 3526: p = 2^b - 2^(b-64) - 1 + 2^64 * ( [2^(b-130) pi] + k )
+	RFC 7919: p = 2^b - 2^(b-64) - 1 + 2^64 * ( [2^(b-130) e]  + k )
+
+with the generator 2. Ez is the exponent size (see linear_group.go), zero for
+secrets modulo q.
+*/
+type modpStandard struct{
+	bits int
+	k    int64
+	e    bool /* RFC 7919 */
+	Ez   int
+}
+
+var modpStandards = map[int]modpStandard{
+	5:  {1536,  741804,false,240},
+	14: {2048,  124476,false,320},
+	15: {3072, 1690314,false,420},
+	16: {4096,  240904,false,480},
+	17: {6144,  929484,false,540},
+	18: {8192, 4743158,false,620},
+	256: {2048,  560316,true,0},
+	257: {3072, 2625351,true,0},
+	258: {4096, 5736041,true,0},
+	259: {6144,15705020,true,0},
+	260: {8192,10965728,true,0},
+}
+
+// Returns [2^prec / x] * arctan(1/x), approximately.
+func arctanInv(x int64, prec uint) *big.Int {
+	X := big.NewInt(x)
+	x2 := big.NewInt(x*x)
+	term := new(big.Int).Lsh(big.NewInt(1),prec)
+	term.Quo(term,X)
+	sum := new(big.Int).Set(term)
+	t := new(big.Int)
+	for n := int64(1); term.Sign()!=0; n++ {
+		term.Quo(term,x2)
+		t.Quo(term,big.NewInt(2*n+1))
+		if n&1==1 { sum.Sub(sum,t) } else { sum.Add(sum,t) }
+	}
+	return sum
+}
+
+// Returns [2^prec * pi] (Machin's formula), or [2^prec * e].
+func constantBits(e bool, prec uint) *big.Int {
+	const guard = 64
+	p := prec+guard
+	var v *big.Int
+	if e {
+		v = new(big.Int)
+		term := new(big.Int).Lsh(big.NewInt(1),p)
+		for n := int64(1); term.Sign()!=0; n++ {
+			v.Add(v,term)
+			term.Quo(term,big.NewInt(n))
+		}
+	}else{
+		v = arctanInv(5,p)
+		v.Lsh(v,2).Sub(v,arctanInv(239,p)).Lsh(v,2)
+	}
+	return v.Rsh(v,guard)
+}
+
+func (s modpStandard) prime() *big.Int {
+	b := uint(s.bits)
+	one := big.NewInt(1)
+	p := new(big.Int).Lsh(one,b)
+	p.Sub(p,new(big.Int).Lsh(one,b-64))
+	p.Sub(p,one)
+	t := constantBits(s.e,b-130)
+	t.Add(t,big.NewInt(s.k))
+	return p.Add(p,t.Lsh(t,64))
+}
+
+// Checks, that the generator has the order (or a multiple of it) of the
+// group: (n-1)*G + G == 0*G. The scalar n-1 is below the order, so groups, that
+// reduce scalars modulo the order (like Curve25519), do not reduce it to zero.
+func checkOrder(g GroupImpl) string {
+	n1 := new(big.Int).Sub(g.Order(),big.NewInt(1))
+	a := g.Encode(g.Add(g.BaseMult(n1),g.BaseMult(big.NewInt(1))))
+	b := g.Encode(g.BaseMult(new(big.Int)))
+	if string(a)!=string(b) { return "generator does not have the group order" }
+	return ""
+}
+
+func checkModPStandard(id int, g *linearGroup) string {
+	s,ok := modpStandards[id]
+	if !ok { return "unknown group" }
+	if g.P.BitLen()!=s.bits { return "wrong bit length" }
+	if g.P.Cmp(s.prime())!=0 { return "prime differs from the standard" }
+	if g.G.Cmp(big.NewInt(2))!=0 { return "generator differs from the standard" }
+	if g.Q.Cmp(new(big.Int).Rsh(g.P,1))!=0 { return "wrong subgroup order" }
+	if g.Ez!=s.Ez { return "wrong exponent size" }
+	return ""
+}
+
+/* The minimum sizes of registered ModP groups. */
+const (
+	minModPBits     = 2048
+	minSubgroupBits = 224
+)
+
+func checkModP(P, G, Q *big.Int) string {
+	one := big.NewInt(1)
+	pm1 := new(big.Int).Sub(P,one)
+	if P.BitLen()<minModPBits || Q.BitLen()<minSubgroupBits { return "group too small" }
+	if !P.ProbablyPrime(32) || !Q.ProbablyPrime(32) { return "not prime" }
+	if new(big.Int).Mod(pm1,Q).Sign()!=0 { return "order does not divide P-1" }
+	if G.Cmp(one)<=0 || G.Cmp(pm1)>=0 { return "generator out of range" }
+	if new(big.Int).Exp(G,Q,P).Cmp(one)!=0 { return "generator does not have the order Q" }
+	return ""
+}
+
+func checkCurve(curve elliptic.Curve) string {
+	p := curve.Params()
+	if p==nil || p.P==nil || p.N==nil || p.Gx==nil || p.Gy==nil { return "incomplete parameters" }
+	if !p.P.ProbablyPrime(32) || !p.N.ProbablyPrime(32) { return "not prime" }
+	if !curve.IsOnCurve(p.Gx,p.Gy) { return "base point not on curve" }
+	/* (0,0) is the point at infinity. */
+	x,y := curve.ScalarBaseMult(p.N.Bytes())
+	if x.Sign()!=0 || y.Sign()!=0 { return "base point does not have the order N" }
+	return ""
+}
+
+/*
+Checks the parameters of every built-in and registered group. The ModP groups
+and the moduli of the complex groups are recomputed from their definition in
+RFC 3526 and RFC 7919. The curves are checked for the bit length, that is part
+of their identifier, and for the order of the base point. Registered groups are
+checked as done by RegisterModPGroup and RegisterCurve. For all groups, the
+order of the generator is checked.
+
+Returns a *SelfTestError for the first failure.
+*/
+func SelfTest() error {
+	var ids []ObjectID
+	for id := range linearGroups { ids = append(ids,ObjectID{group_ModP,id}) }
+	for _,id := range groups {
+		if id[0]!=group_ModP { ids = append(ids,id) }
+	}
+	registry.RLock()
+	for _,r := range registry.byID { ids = append(ids,r.id) }
+	registry.RUnlock()
+	
+	for _,id := range ids {
+		g := getGroupImpl(id)
+		if g==nil { return &SelfTestError{id,"no implementation"} }
+		var msg string
+		if id[0]>group_Reserved {
+			switch v := g.(type) {
+			case *linearGroup: msg = checkModP(v.P,v.G,v.Q)
+			case *curveGroup: msg = checkCurve(v.curve)
+			}
+		}else{
+			switch id[0] {
+			case group_ModP:
+				msg = checkModPStandard(id[1],g.(*linearGroup))
+			case group_ComplxGroup:
+				/* The moduli are the RFC 3526 primes of groups 14, 16 and 18. */
+				m := complexGroups[id[1]].Modulus
+				s := map[int]int{1:14,2:16,3:18}[id[1]]
+				if m.Cmp(modpStandards[s].prime())!=0 { msg = "modulus differs from the standard" }
+			case group_EcFips,group_EcKoblitz,group_EcBrainpool:
+				curve := g.(*curveGroup).curve
+				if curve.Params().BitSize!=id[1] { msg = "wrong bit length" }
+				if msg=="" { msg = checkCurve(curve) }
+			}
+		}
+		if msg=="" { msg = checkOrder(g) }
+		if msg!="" { return &SelfTestError{id,msg} }
+	}
+	return nil
+}
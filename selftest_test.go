@@ -0,0 +1,50 @@
+/*
+MIT License
+
+Copyright (c) 2017 Simon Schmidt
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+
+package generalcryptosystem
+
+import "testing"
+import "math/big"
+
+func TestSelfTest(t *testing.T) {
+	if e := SelfTest(); e!=nil { t.Fatal(e) }
+	old := linearGroups[17]
+	bad := *old
+	bad.P = linearGroups[14].P
+	linearGroups[17] = &bad
+	e := SelfTest()
+	linearGroups[17] = old
+	if e==nil { t.Fatal("bad group accepted") }
+}
+
+type wrongOrder struct{ GroupImpl }
+func (w wrongOrder) Order() *big.Int { return new(big.Int).Add(c25519Order,big.NewInt(2)) }
+
+func TestCheckOrder(t *testing.T) {
+	for _,g := range []GroupImpl{c25519Group{false},c25519Group{true},r255Group{}} {
+		if s := checkOrder(g); s!="" { t.Fatal(g,s) }
+		if checkOrder(wrongOrder{g})=="" { t.Fatal(g,"wrong order accepted") }
+	}
+}
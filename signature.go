@@ -26,11 +26,40 @@ package generalcryptosystem
 
 import "io"
 import "hash"
+import "bytes"
 import "math/big"
-import "crypto/rand"
+import "encoding/asn1"
 import "golang.org/x/crypto/blake2b"
 import "crypto/subtle"
 
+/*
+Signatures are Schnorr signatures. The commitment R = g^k is hashed into the
+key K (BLAKE2b-512 of R if R is longer than 64 bytes) and the challenge is
+e = BLAKE2b-512 keyed with K.
+
+SigLegacy: The challenge is computed over the message, so k has to be chosen
+before the message is known. k is drawn at random from [x*2^512, x*2^513) and
+s = k - x*e is computed over the integers.
+
+SigCompact: The challenge is computed over the BLAKE2b-512 hash of the message.
+The nonce k is derived (see kdf.go) from the secret, the message hash and
+optional fresh randomness, so a broken or missing source of randomness does not
+leak the secret. The challenge and s = k - x*e are reduced modulo the group
+order n, so both values have a fixed width: the challenge is stored in Hash
+with min(64,len(n)) bytes, s is in [0,n). Verify rejects any signature outside
+of these ranges. This is the scheme used by Sign.
+
+The group order n is the order of the generator, (P-1)/2 for the ModP groups.
+For the complex groups, it is p^2-1, a multiple of the order.
+*/
+
+const (
+	SigLegacy = 0
+	SigCompact = 1
+)
+
+const sigLabelNonce = "generalcryptosystem signature nonce"
+
 type Signer interface {
 	io.Writer
 	
@@ -42,82 +71,236 @@ type Verifier interface {
 	Verify() bool
 }
 
+// Returns the width of the challenge and of s for SigCompact signatures.
+func sigWidths(n *big.Int) (int,int) {
+	sw := (n.BitLen()+7)/8
+	if sw>64 { return 64,sw }
+	return sw,sw
+}
+
+// Computes the hash key from the commitment g^k.
+func commitKey(group ObjectID, k *big.Int) ([]byte,error) {
+	g := getGroupImpl(group)
+	if g==nil { return nil,EInvalidGroup }
+	return hashKey(legacyBytes(g,g.BaseMult(k))),nil
+}
+
+// Computes the hash key from g^s * y^e.
+func verifyKey(pub *PublicKey, s, e *big.Int) ([]byte,error) {
+	g := getGroupImpl(pub.Group)
+	if g==nil { return nil,EInvalidGroup }
+	Y,err := g.KeyElement(pub)
+	if err!=nil { return nil,err }
+	return hashKey(legacyBytes(g,g.Add(g.BaseMult(s),g.Mult(e,Y)))),nil
+}
+
+func hashKey(K []byte) []byte {
+	if len(K)>64 {
+		sum := blake2b.Sum512(K)
+		K = sum[:]
+	}
+	return K
+}
+
 type signer struct {
 	io.Writer
 	h hash.Hash
-	k *big.Int
+	group ObjectID
+	n *big.Int
 	x *big.Int
+	rnd []byte
 }
+
+// Creates a Signer using the SigCompact scheme. The nonce is derived from the
+// secret, the message and 32 bytes read from r. If r is nil, the signature
+// is deterministic.
 func Sign(priv *PrivateKey,r io.Reader) (Signer,error) {
-	M := new(big.Int).Lsh(priv.Secret,512)
-	k,e := rand.Int(r, M)
-	if e!=nil { return nil,e }
-	k = new(big.Int).Add(k,M)
-	var K []byte
-	
-	if len(priv.Group)<2 { return nil,EInvalidGroup }
-	
-	if priv.Group[0]==group_ModP {
-		Ke,e := modpExp(priv.Group[1],k)
-		if e!=nil { return nil,EInvalidGroup }
-		K = Ke.Bytes()
-	}else  if curve := getCurve(priv.Group); curve!=nil {
-		x,y := curve.ScalarBaseMult(k.Bytes())
-		K = append(x.Bytes(),y.Bytes()...)
-	}else { return nil,EInvalidGroup }
-	
-	if len(K)>64 {
-		sum := blake2b.Sum512(K)
-		K = sum[:]
+	return newSigner(priv,r,newBlake2b512())
+}
+
+// Creates a Signer with the given transcript hash, which must be a BLAKE2b-512.
+func newSigner(priv *PrivateKey, r io.Reader, h hash.Hash) (Signer,error) {
+	n := groupOrder(priv.Group)
+	if n==nil { return nil,EInvalidGroup }
+	if priv.Secret==nil { return nil,EInvalidKey }
+	if e := priv.PublicKey().Validate(); e!=nil { return nil,e }
+	var rnd []byte
+	if r!=nil {
+		rnd = make([]byte,32)
+		_,e := io.ReadFull(r,rnd)
+		if e!=nil { return nil,e }
 	}
+	return &signer{h,h,priv.Group,n,priv.Secret,rnd},nil
+}
+func (s *signer) Sign() *Signature {
+	m := s.h.Sum(make([]byte,0,64))
+	gid,_ := asn1.Marshal(s.group)
 	
+	/* k is taken from [1,n), with 64 extra bits to make the bias negligible. */
+	nm1 := new(big.Int).Sub(s.n,big.NewInt(1))
+	kb := make([]byte,(s.n.BitLen()+7)/8+8)
+	io.ReadFull(kdf(s.x.Bytes(),s.rnd,sigLabelNonce,gid,m),kb)
+	k := new(big.Int).SetBytes(kb)
+	k.Mod(k,nm1).Add(k,big.NewInt(1))
+	
+	K,err := commitKey(s.group,k)
+	if err!=nil { return nil }
 	h,_ := blake2b.New512(K)
+	h.Write(m)
+	hs := h.Sum(make([]byte,0,64))
 	
-	return &signer{h,h,k,priv.Secret},nil
-}
-func (s *signer) Sign() *Signature {
-	h := s.h.Sum(make([]byte,0,64))
-	e := new(big.Int).SetBytes(h)
+	e := new(big.Int).SetBytes(hs)
+	e.Mod(e,s.n)
+	ew,_ := sigWidths(s.n)
+	hs = e.FillBytes(make([]byte,ew))
 	xe := new(big.Int).Mul(s.x,e)
-	sig := e.Sub(s.k,xe)
-	return &Signature{sig,h}
+	sig := xe.Sub(k,xe)
+	sig.Mod(sig,s.n)
+	return &Signature{sig,hs,SigCompact,s.group}
 }
 
 type verifier struct {
 	io.Writer
 	h hash.Hash
+	mac hash.Hash
+	n *big.Int /* If set, the MAC is reduced modulo n. */
 	should []byte
 }
+// Creates a Verifier for the signature. If the signature names its group, it
+// must be the group of pub.
 func Verify(pub *PublicKey, sig *Signature) (Verifier,error) {
-	if len(pub.Group)<2 { return nil,EInvalidGroup }
-	var K []byte
-	if pub.Group[0]==group_ModP {
-		g,ok := linearGroups[pub.Group[1]]
-		if !ok { return nil,EInvalidGroup }
-		gs := new(big.Int).Exp(g.G,sig.Sig,g.P)
-		ye := new(big.Int).Exp(pub.X,new(big.Int).SetBytes(sig.Hash),g.P)
-		gsye := new(big.Int).Mul(gs,ye)
-		Ke := gs.Mod(gsye,g.P)
-		K = Ke.Bytes()
-	}else  if curve := getCurve(pub.Group); curve!=nil {
-		gsx,gsy := curve.ScalarBaseMult(sig.Sig.Bytes())
-		yex,yey := curve.ScalarMult(pub.X,pub.Y,sig.Hash)
-		x,y := curve.Add(gsx,gsy,yex,yey)
-		K = append(x.Bytes(),y.Bytes()...)
-	}else { return nil,EInvalidGroup }
-	
-	if len(K)>64 {
-		sum := blake2b.Sum512(K)
-		K = sum[:]
+	return newVerifier(pub,sig,newBlake2b512())
+}
+
+// Creates a Verifier with the given transcript hash, like newSigner. The hash
+// is not used by SigLegacy.
+func newVerifier(pub *PublicKey, sig *Signature, h hash.Hash) (Verifier,error) {
+	if e := pub.Validate(); e!=nil { return nil,e }
+	if sig.Group!=nil && sig.Group.key()!=pub.Group.key() { return nil,EGroupMismatch }
+	/*
+	A negative s would lose its sign in the scalar multiplication on curves,
+	and is never produced by any of the schemes. An invalid signature gives a
+	verifier, that never matches.
+	*/
+	invalid := sig.Sig==nil || sig.Sig.Sign()<0
+	switch sig.Version {
+	case SigLegacy:
+		if invalid { return &verifier{h,h,nil,nil,nil},nil }
+		K,e := verifyKey(pub,sig.Sig,new(big.Int).SetBytes(sig.Hash))
+		if e!=nil { return nil,e }
+		h,_ := blake2b.New512(K)
+		return &verifier{h,h,nil,nil,sig.Hash},nil
+	case SigCompact:
+		n := groupOrder(pub.Group)
+		if n==nil { return nil,EInvalidGroup }
+		ch := new(big.Int).SetBytes(sig.Hash)
+		ew,_ := sigWidths(n)
+		if len(sig.Hash)!=ew || ch.Cmp(n)>=0 { invalid = true }
+		if invalid || sig.Sig.Cmp(n)>=0 { return &verifier{h,h,nil,nil,nil},nil }
+		K,e := verifyKey(pub,sig.Sig,ch)
+		if e!=nil { return nil,e }
+		mac,_ := blake2b.New512(K)
+		return &verifier{h,h,mac,n,sig.Hash},nil
 	}
-	
-	h,_ := blake2b.New512(K)
-	
-	return &verifier{h,h,sig.Hash},nil
+	return nil,EUnsupportedVersion
 }
 func (v *verifier) Verify() bool {
 	h := v.h.Sum(make([]byte,0,64))
+	if v.mac!=nil {
+		v.mac.Write(h)
+		h = v.mac.Sum(make([]byte,0,64))
+	}
+	if v.n!=nil {
+		ew,_ := sigWidths(v.n)
+		c := new(big.Int).SetBytes(h)
+		h = c.Mod(c,v.n).FillBytes(make([]byte,ew))
+	}
 	return subtle.ConstantTimeCompare(h,v.should) == 1
 }
 
+/*
+The binary form of a signature (MarshalBinary) is the DER encoding of
 
+	SEQUENCE {
+		group     the group of the signer (see oids.go)
+		version   INTEGER (SigLegacy or SigCompact)
+		challenge OCTET STRING (the Hash field)
+		s         INTEGER (the Sig field)
+	}
+
+Only values, that Sign (or the older scheme) can produce, are accepted: s is
+not negative, the challenge has 64 bytes, or the width of SigCompact, and the
+values are below the group order, where the scheme reduces them. The DER rules
+make the encoding unique, any other encoding of the same values is rejected.
+*/
+type signatureData struct{
+	Group     asn1.RawValue
+	Version   int
+	Challenge []byte
+	S         *big.Int
+}
+
+// Checks the ranges of the values of the signature.
+func (sig *Signature) check() error {
+	n := groupOrder(sig.Group)
+	if n==nil { return EInvalidGroup }
+	if sig.Sig==nil || sig.Sig.Sign()<0 { return EInvalidSignature }
+	switch sig.Version {
+	case SigLegacy:
+		if len(sig.Hash)!=64 { return EInvalidSignature }
+	case SigCompact:
+		ew,_ := sigWidths(n)
+		if len(sig.Hash)!=ew || new(big.Int).SetBytes(sig.Hash).Cmp(n)>=0 || sig.Sig.Cmp(n)>=0 { return EInvalidSignature }
+	default:
+		return EUnsupportedVersion
+	}
+	return nil
+}
+
+// Encodes the signature, including its group and scheme.
+func (sig *Signature) MarshalBinary() ([]byte,error) {
+	if e := sig.check(); e!=nil { return nil,e }
+	gid,e := marshalGroupID(sig.Group)
+	if e!=nil { return nil,e }
+	return asn1.Marshal(signatureData{gid,sig.Version,sig.Hash,sig.Sig})
+}
+
+// Decodes the output of MarshalBinary. Values out of range and non-canonical
+// encodings are rejected with EInvalidSignature.
+func (sig *Signature) UnmarshalBinary(b []byte) error {
+	var d signatureData
+	rest,e := asn1.Unmarshal(b,&d)
+	if e!=nil || len(rest)!=0 { return EInvalidSignature }
+	group,e := parseGroupID(d.Group)
+	if e!=nil { return e }
+	s := &Signature{d.S,d.Challenge,d.Version,group}
+	if e = s.check(); e!=nil { return e }
+	c,e := s.MarshalBinary()
+	if e!=nil || !bytes.Equal(c,b) { return EInvalidSignature }
+	*sig = *s
+	return nil
+}
+
+// Returns the fixed-width encoding of a SigCompact signature: the challenge
+// followed by s, both as big endian numbers with the widths of the group.
+func (sig *Signature) Compact(group ObjectID) ([]byte,error) {
+	n := groupOrder(group)
+	if n==nil { return nil,EInvalidGroup }
+	ew,sw := sigWidths(n)
+	if sig.Version!=SigCompact || len(sig.Hash)!=ew || sig.Sig==nil || sig.Sig.Sign()<0 || sig.Sig.Cmp(n)>=0 { return nil,EInvalidSignature }
+	b := make([]byte,ew+sw)
+	copy(b,sig.Hash)
+	sig.Sig.FillBytes(b[ew:])
+	return b,nil
+}
+
+// Parses the output of Signature.Compact. Values out of range are rejected.
+func ParseCompact(group ObjectID, b []byte) (*Signature,error) {
+	n := groupOrder(group)
+	if n==nil { return nil,EInvalidGroup }
+	ew,sw := sigWidths(n)
+	if len(b)!=ew+sw { return nil,EInvalidSignature }
+	s := new(big.Int).SetBytes(b[ew:])
+	if new(big.Int).SetBytes(b[:ew]).Cmp(n)>=0 || s.Cmp(n)>=0 { return nil,EInvalidSignature }
+	return &Signature{s,append([]byte(nil),b[:ew]...),SigCompact,group},nil
+}
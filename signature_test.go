@@ -0,0 +1,170 @@
+/*
+MIT License
+
+Copyright (c) 2017 Simon Schmidt
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+
+package generalcryptosystem
+
+import "os"
+import "bytes"
+import "testing"
+import "math/big"
+import "crypto/rand"
+import "encoding/asn1"
+import "golang.org/x/crypto/blake2b"
+
+/* Signs msg with SigLegacy, as Sign did before SigCompact. */
+func legacySign(t *testing.T, priv *PrivateKey, msg []byte) *Signature {
+	M := new(big.Int).Lsh(priv.Secret,512)
+	k,_ := rand.Int(rand.Reader,M)
+	k.Add(k,M)
+	K,e := commitKey(priv.Group,k)
+	if e!=nil { t.Fatal(e) }
+	h,_ := blake2b.New512(K)
+	h.Write(msg)
+	hs := h.Sum(nil)
+	return &Signature{Sig:new(big.Int).Sub(k,new(big.Int).Mul(priv.Secret,new(big.Int).SetBytes(hs))),Hash:hs}
+}
+
+func verifies(t *testing.T, pub *PublicKey, sig *Signature, msg []byte) bool {
+	v,e := Verify(pub,sig)
+	if e!=nil { t.Fatal(pub.Group,e) }
+	v.Write(msg)
+	return v.Verify()
+}
+
+func signBytes(t *testing.T, priv *PrivateKey, r *bytes.Reader, msg []byte) *Signature {
+	var s Signer
+	var e error
+	if r==nil {
+		s,e = Sign(priv,nil)
+	} else {
+		s,e = Sign(priv,r)
+	}
+	if e!=nil { t.Fatal(priv.Group,e) }
+	s.Write(msg)
+	return s.Sign()
+}
+
+func TestSignGroups(t *testing.T) {
+	msg := []byte("the message")
+	for _,g := range testGroups {
+		pub,priv := testKeys(t,g)
+		n := groupOrder(priv.Group)
+		d1,d2 := signBytes(t,priv,nil,msg),signBytes(t,priv,nil,msg)
+		if d1.Sig.Cmp(d2.Sig)!=0 || !bytes.Equal(d1.Hash,d2.Hash) { t.Fatal(g,"not deterministic") }
+		h1 := signBytes(t,priv,bytes.NewReader(testMessage(64)),msg)
+		if d1.Sig.Cmp(h1.Sig)==0 { t.Fatal(g,"randomness not used") }
+		for _,s := range []*Signature{d1,h1} {
+			if s.Version!=SigCompact || s.Sig.Cmp(n)>=0 { t.Fatal(g,"version or range") }
+			if !verifies(t,pub,s,msg) || verifies(t,pub,s,msg[1:]) { t.Fatal(g,"verify") }
+			bad := *s
+			bad.Sig = new(big.Int).Add(s.Sig,n)
+			if verifies(t,pub,&bad,msg) { t.Fatal(g,"s >= n accepted") }
+			bad = *s
+			bad.Hash = append([]byte{0},s.Hash...)
+			if verifies(t,pub,&bad,msg) { t.Fatal(g,"wide challenge accepted") }
+			bad = *s
+			bad.Version = SigLegacy
+			if verifies(t,pub,&bad,msg) { t.Fatal(g,"version confusion") }
+			bad.Version = 2
+			if _,e := Verify(pub,&bad); e!=EUnsupportedVersion { t.Fatal(g,e) }
+		}
+		ls := legacySign(t,priv,msg)
+		if !verifies(t,pub,ls,msg) || verifies(t,pub,ls,msg[1:]) { t.Fatal(g,"legacy") }
+		neg := *ls
+		neg.Sig = new(big.Int).Neg(ls.Sig)
+		if verifies(t,pub,&neg,msg) { t.Fatal(g,"negative legacy accepted") }
+	}
+}
+
+func TestSignBaseline(t *testing.T) {
+	for _,name := range baselineNames {
+		pub,_ := readBaseline(t,name)
+		b,e := os.ReadFile("testdata/baseline/"+name+".sig")
+		if e!=nil { t.Fatal(e) }
+		sig := new(Signature)
+		if _,e = asn1.Unmarshal(b,sig); e!=nil { t.Fatal(name,e) }
+		if sig.Version!=SigLegacy || !verifies(t,pub,sig,[]byte("signed")) || verifies(t,pub,sig,[]byte("Signed")) { t.Fatal(name) }
+	}
+}
+
+func TestSignCompact(t *testing.T) {
+	msg := []byte("the message")
+	for _,g := range testGroups {
+		pub,priv := testKeys(t,g)
+		sig := signBytes(t,priv,nil,msg)
+		c,e := sig.Compact(priv.Group)
+		if e!=nil { t.Fatal(g,e) }
+		ew,sw := sigWidths(groupOrder(priv.Group))
+		if len(c)!=ew+sw { t.Fatal(g,"width",len(c)) }
+		p,e := ParseCompact(priv.Group,c)
+		if e!=nil || !verifies(t,pub,p,msg) { t.Fatal(g,e) }
+		if _,e = ParseCompact(priv.Group,c[1:]); e==nil { t.Fatal(g,"short accepted") }
+		if _,e = legacySign(t,priv,msg).Compact(priv.Group); e!=EInvalidSignature { t.Fatal(g,e) }
+	}
+}
+
+func TestSignZeroSecret(t *testing.T) {
+	_,priv := testKeys(t,FIPS_P256)
+	priv.Secret = new(big.Int)
+	if _,e := Sign(priv,nil); e!=EIdentityElement { t.Fatal(e) }
+}
+
+func TestSignatureBinary(t *testing.T) {
+	for _,g := range testGroups {
+		pub,priv := testKeys(t,g)
+		sig := signBytes(t,priv,nil,[]byte("m"))
+		b,e := sig.MarshalBinary()
+		if e!=nil { t.Fatal(g,e) }
+		sig2 := new(Signature)
+		if e = sig2.UnmarshalBinary(b); e!=nil || !verifies(t,pub,sig2,[]byte("m")) { t.Fatal(g,e) }
+		
+		/* The plain asn1 form of the baseline has no group. */
+		old,_ := asn1.Marshal(Signature{sig.Sig,sig.Hash,sig.Version,nil})
+		var so Signature
+		if _,e = asn1.Unmarshal(old,&so); e!=nil || so.Group!=nil || !verifies(t,pub,&so,[]byte("m")) { t.Fatal(g,e) }
+		
+		/* Non-canonical encodings. */
+		if b[1]<0x80 {
+			if sig2.UnmarshalBinary(append([]byte{0x30,0x81,b[1]},b[2:]...))==nil { t.Fatal(g,"long form length accepted") }
+		}
+		if sig2.UnmarshalBinary(append(b,0))==nil { t.Fatal(g,"trailing data accepted") }
+		gid,_ := marshalGroupID(sig.Group)
+		bb,_ := asn1.Marshal(signatureData{gid,sig.Version,sig.Hash,new(big.Int).Add(sig.Sig,groupOrder(priv.Group))})
+		if e = sig2.UnmarshalBinary(bb); e!=EInvalidSignature { t.Fatal(g,"s >= n",e) }
+		bb,_ = asn1.Marshal(signatureData{gid,sig.Version,sig.Hash,new(big.Int).Neg(sig.Sig)})
+		if e = sig2.UnmarshalBinary(bb); e!=EInvalidSignature { t.Fatal(g,"negative s",e) }
+		bb,_ = asn1.Marshal(signatureData{gid,7,sig.Hash,sig.Sig})
+		if e = sig2.UnmarshalBinary(bb); e!=EUnsupportedVersion { t.Fatal(g,e) }
+		
+		/* Groups with an OID must not use the ObjectID form. */
+		lg,_ := asn1.Marshal(sig.Group)
+		bb,_ = asn1.Marshal(signatureData{asn1.RawValue{FullBytes:lg},sig.Version,sig.Hash,sig.Sig})
+		e = sig2.UnmarshalBinary(bb)
+		if (g.OID()!=nil)!=(e==EInvalidSignature) { t.Fatal(g,"group form",e) }
+		
+		other,_ := testKeys(t,FIPS_P384)
+		if _,e = Verify(other,sig); e!=EGroupMismatch { t.Fatal(g,e) }
+	}
+}
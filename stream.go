@@ -0,0 +1,170 @@
+/*
+MIT License
+
+Copyright (c) 2017 Simon Schmidt
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package generalcryptosystem
+
+import "io"
+import "crypto/cipher"
+import "encoding/binary"
+
+/*
+Segmented stream of the envelope, modeled after the STREAM construction.
+
+The plaintext is split into segments of segmentSize bytes. Every segment but
+the last one is full, the last segment is empty only if the whole plaintext is
+empty. The nonce of a segment consists of the segment counter followed by a
+single flag byte, which is 1 for the last segment and 0 otherwise:
+
+	nonce = counter (11 bytes, big endian) || final flag (1 byte)
+
+As the counter is part of the nonce, reordered segments fail to open. As the
+final flag is part of the nonce, a stream cut at a segment boundary is detected
+(the last segment received is not flagged as final) as well as segments
+appended after the final one.
+*/
+
+const segmentSize = 1<<16
+
+func segmentNonce(aead cipher.AEAD, ctr uint64, last bool) []byte {
+	n := make([]byte,aead.NonceSize())
+	binary.BigEndian.PutUint64(n[len(n)-9:],ctr)
+	if last { n[len(n)-1] = 1 }
+	return n
+}
+
+type segmentWriter struct{
+	dest io.Writer
+	clos io.Closer
+	aead cipher.AEAD
+	ad   []byte
+	ctr  uint64
+	buf  []byte
+	out  []byte
+	done bool
+}
+func (w *segmentWriter) seal(last bool) error {
+	w.out = w.aead.Seal(w.out[:0],segmentNonce(w.aead,w.ctr,last),w.buf,w.ad)
+	w.ctr++
+	w.buf = w.buf[:0]
+	_,e := w.dest.Write(w.out)
+	return e
+}
+func (w *segmentWriter) Write(p []byte) (n int, err error) {
+	if w.done { return 0,io.ErrClosedPipe }
+	for len(p)>0 {
+		/*
+		A full segment is sealed, once more data arrives. Until then, it
+		might be the last one.
+		*/
+		if len(w.buf)==cap(w.buf) {
+			err = w.seal(false)
+			if err!=nil { return }
+		}
+		i := copy(w.buf[len(w.buf):cap(w.buf)],p)
+		w.buf = w.buf[:len(w.buf)+i]
+		p = p[i:]
+		n += i
+	}
+	return
+}
+func (w *segmentWriter) Close() error {
+	if w.done { return nil }
+	w.done = true
+	e := w.seal(true)
+	if e!=nil { return e }
+	if w.clos==nil { return nil }
+	return w.clos.Close()
+}
+
+/*
+Opens the segment with the given counter. If it fails, it is tried again with
+the final flag inverted, to tell a truncated stream (ETruncated) or data
+appended after the final segment (ETrailingData) apart from a corrupted or
+misplaced segment (EAuthFailed).
+*/
+func openSegment(aead cipher.AEAD, ctr uint64, last bool, dst, ct, ad []byte) ([]byte,error) {
+	plain,e := aead.Open(dst,segmentNonce(aead,ctr,last),ct,ad)
+	if e==nil { return plain,nil }
+	_,e = aead.Open(dst,segmentNonce(aead,ctr,!last),ct,ad)
+	if e!=nil { return nil,EAuthFailed }
+	if last { return nil,ETruncated }
+	return nil,ETrailingData
+}
+
+/*
+Reads one segment ahead by a single byte, to find out, whether the current
+segment is the last one. Only one segment is held in memory at any time.
+*/
+type segmentReader struct{
+	src   io.Reader
+	aead  cipher.AEAD
+	ad    []byte
+	ctr   uint64
+	buf   []byte
+	n     int
+	out   []byte
+	plain []byte
+	e     error
+}
+func newSegmentReader(src io.Reader, aead cipher.AEAD, ad []byte) *segmentReader {
+	d := new(segmentReader)
+	d.src  = src
+	d.aead = aead
+	d.ad   = ad
+	d.buf  = make([]byte,segmentSize+aead.Overhead()+1)
+	d.out  = make([]byte,0,segmentSize)
+	return d
+}
+func (d *segmentReader) next() {
+	m,e := io.ReadFull(d.src,d.buf[d.n:])
+	d.n += m
+	if e!=nil && e!=io.EOF && e!=io.ErrUnexpectedEOF { d.e = e; return }
+	last := d.n<len(d.buf)
+	l := d.n
+	if !last { l-- }
+	if l<d.aead.Overhead() {
+		d.e = ETruncated
+		return
+	}
+	d.plain,e = openSegment(d.aead,d.ctr,last,d.out[:0],d.buf[:l],d.ad)
+	if e!=nil {
+		d.e = e
+		return
+	}
+	d.ctr++
+	if last {
+		d.e = io.EOF
+		return
+	}
+	d.buf[0] = d.buf[l]
+	d.n = 1
+}
+func (d *segmentReader) Read(p []byte) (n int, err error) {
+	for len(d.plain)==0 && d.e==nil { d.next() }
+	n = copy(p,d.plain)
+	d.plain = d.plain[n:]
+	if len(d.plain)==0 { err = d.e }
+	return
+}
+
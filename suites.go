@@ -0,0 +1,96 @@
+/*
+MIT License
+
+Copyright (c) 2017 Simon Schmidt
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package generalcryptosystem
+
+import "io"
+import "crypto/aes"
+import "crypto/cipher"
+import "golang.org/x/crypto/chacha20poly1305"
+
+/*
+A Suite is the symmetric cipher, the payload of an envelope is sealed with.
+The identifier of the suite is stored in the envelope header.
+*/
+type Suite uint
+const (
+	ChaCha20Poly1305 = Suite(iota) /* ChaCha20-Poly1305 (see RFC-8439), default */
+	AES256GCM /* AES in 256-bit mode with GCM */
+	XChaCha20Poly1305 /* ChaCha20-Poly1305 with extended 192-bit nonce */
+	TwofishCBC /* Twofish in 256-bit CBC mode with BLAKE2b MAC */
+	
+	/*
+	Twofish in 256-bit CBC mode without authentication. Only reported by
+	ParseHeader for the legacy format, it can not be used for encryption.
+	*/
+	LegacyTwofish
+)
+
+type suiteImpl struct{
+	Name    string
+	KeySize int /* Size of the cipher key */
+	MacSize int /* Size of the separate MAC key, 0 for AEAD ciphers */
+	New     func(key, mac []byte) (cipher.AEAD,error)
+}
+
+var suites = make(map[Suite]suiteImpl)
+
+func (s Suite) Valid() bool{
+	_,ok := suites[s]
+	return ok
+}
+func (s Suite) String() string {
+	if r,ok := suites[s]; ok { return r.Name }
+	if s==LegacyTwofish { return "Twofish-256-CBC (unauthenticated)" }
+	return "invalid suite"
+}
+
+func getSuite(s Suite) (suiteImpl,error) {
+	if r,ok := suites[s]; ok { return r,nil }
+	return suiteImpl{},EInvalidSuite
+}
+
+// Reads the cipher key and the MAC key from the output of the KDF.
+func (s suiteImpl) derive(kdf io.Reader) (cipher.AEAD,error) {
+	k := make([]byte,s.KeySize+s.MacSize)
+	_,e := io.ReadFull(kdf,k)
+	if e!=nil { return nil,e }
+	return s.New(k[:s.KeySize],k[s.KeySize:])
+}
+
+func newChaCha20(key, mac []byte) (cipher.AEAD,error) { return chacha20poly1305.New(key) }
+func newXChaCha20(key, mac []byte) (cipher.AEAD,error) { return chacha20poly1305.NewX(key) }
+func newAESGCM(key, mac []byte) (cipher.AEAD,error) {
+	c,e := aes.NewCipher(key)
+	if e!=nil { return nil,e }
+	return cipher.NewGCM(c)
+}
+
+func init(){
+	suites[ChaCha20Poly1305]  = suiteImpl{"ChaCha20-Poly1305",32,0,newChaCha20}
+	suites[AES256GCM]         = suiteImpl{"AES-256-GCM",32,0,newAESGCM}
+	suites[XChaCha20Poly1305] = suiteImpl{"XChaCha20-Poly1305",32,0,newXChaCha20}
+	suites[TwofishCBC]        = suiteImpl{"Twofish-256-CBC-BLAKE2b",32,32,newTwofishCBC}
+}
+
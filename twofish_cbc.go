@@ -0,0 +1,114 @@
+/*
+MIT License
+
+Copyright (c) 2017 Simon Schmidt
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package generalcryptosystem
+
+import "crypto/cipher"
+import "crypto/subtle"
+import "encoding/binary"
+import "golang.org/x/crypto/blake2b"
+import "golang.org/x/crypto/twofish"
+
+/*
+Twofish in CBC mode as cipher.AEAD (encrypt-then-MAC).
+
+It takes a 256-bit cipher key and a separate 256-bit MAC key. The IV is derived
+from the nonce. The plaintext is padded like in the legacy
+format, encrypted and then authenticated with keyed BLAKE2b-256 over the
+additional data, the nonce and the ciphertext.
+*/
+type twofishCBC struct{
+	block cipher.Block
+	mac   []byte
+}
+
+const twofishCBCTag = 32
+
+func newTwofishCBC(key, mac []byte) (cipher.AEAD,error) {
+	c,e := twofish.NewCipher(key)
+	if e!=nil { return nil,e }
+	return &twofishCBC{c,mac},nil
+}
+
+func (t *twofishCBC) NonceSize() int { return 12 }
+func (t *twofishCBC) Overhead() int { return twofish.BlockSize+twofishCBCTag }
+
+func (t *twofishCBC) iv(nonce []byte) []byte {
+	iv := make([]byte,twofish.BlockSize)
+	copy(iv[twofish.BlockSize-len(nonce):],nonce)
+	t.block.Encrypt(iv,iv)
+	return iv
+}
+func (t *twofishCBC) tag(nonce, ciphertext, ad []byte) []byte {
+	var l [16]byte
+	h,_ := blake2b.New256(t.mac)
+	binary.BigEndian.PutUint64(l[:8],uint64(len(ad)))
+	binary.BigEndian.PutUint64(l[8:],uint64(len(ciphertext)))
+	h.Write(l[:])
+	h.Write(ad)
+	h.Write(nonce)
+	h.Write(ciphertext)
+	return h.Sum(nil)
+}
+
+func (t *twofishCBC) Seal(dst, nonce, plaintext, ad []byte) []byte {
+	if len(nonce)!=t.NonceSize() { panic("generalcryptosystem: incorrect nonce length") }
+	ps := twofish.BlockSize-len(plaintext)%twofish.BlockSize
+	l := len(plaintext)+ps
+	ret,out := sliceForAppend(dst,l+twofishCBCTag)
+	ct := out[:l]
+	copy(ct,plaintext)
+	for i := len(plaintext); i<l; i++ { ct[i] = byte(ps) }
+	cipher.NewCBCEncrypter(t.block,t.iv(nonce)).CryptBlocks(ct,ct)
+	copy(out[l:],t.tag(nonce,ct,ad))
+	return ret
+}
+
+func (t *twofishCBC) Open(dst, nonce, ciphertext, ad []byte) ([]byte,error) {
+	if len(nonce)!=t.NonceSize() { return nil,EAuthFailed }
+	l := len(ciphertext)-twofishCBCTag
+	if l<twofish.BlockSize || l%twofish.BlockSize!=0 { return nil,EAuthFailed }
+	ct := ciphertext[:l]
+	if subtle.ConstantTimeCompare(t.tag(nonce,ct,ad),ciphertext[l:])!=1 { return nil,EAuthFailed }
+	
+	ret,plain := sliceForAppend(dst,l)
+	cipher.NewCBCDecrypter(t.block,t.iv(nonce)).CryptBlocks(plain,ct)
+	ps := int(plain[l-1])
+	if ps<1 || ps>twofish.BlockSize { return nil,EAuthFailed }
+	return ret[:len(ret)-ps],nil
+}
+
+// Extends in by n bytes, reusing its capacity (so in-place operation is
+// possible), and returns the whole slice and the appended part.
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in)+n; cap(in)>=total {
+		head = in[:total]
+	}else{
+		head = make([]byte,total)
+		copy(head,in)
+	}
+	tail = head[len(in):]
+	return
+}
+